@@ -1,6 +1,9 @@
 package router
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
 
 	"github.com/gopi-frame/contract"
@@ -10,6 +13,9 @@ import (
 
 type Kernel struct {
 	*http.Server
+
+	// Admin serves administrative endpoints, e.g. metrics, apart from the application, see [WithAdmin].
+	Admin *http.Server
 }
 
 type Option = contract.Option[*http.Server]
@@ -27,20 +33,72 @@ func WithAddr(addr string) Option {
 	})
 }
 
+// adminOption configures the admin server of the kernel, see [WithAdmin].
+type adminOption struct {
+	addr    string
+	handler http.Handler
+}
+
+// Apply does nothing to the application server, since the admin server is created by [NewKernel].
+func (o adminOption) Apply(*http.Server) error {
+	return nil
+}
+
+// WithAdmin serves the handler, e.g. metrics, on an admin server listening on the address,
+// which runs along with the application server.
+func WithAdmin(addr string, handler http.Handler) Option {
+	return adminOption{addr: addr, handler: handler}
+}
+
 func NewKernel(r router.Router, opts ...Option) (*Kernel, error) {
 	srv := &http.Server{
 		Handler: r,
 	}
+	kernel := &Kernel{
+		Server: srv,
+	}
 	for _, opt := range opts {
+		if admin, ok := opt.(adminOption); ok {
+			kernel.Admin = &http.Server{
+				Addr:    admin.addr,
+				Handler: admin.handler,
+			}
+			continue
+		}
 		if err := opt.Apply(srv); err != nil {
 			return nil, err
 		}
 	}
-	return &Kernel{
-		Server: srv,
-	}, nil
+	return kernel, nil
 }
 
 func (k *Kernel) Run() error {
+	// report misconfigured routes before accepting requests
+	if v, ok := k.Handler.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	if k.Admin != nil {
+		// listen before serving the application, so that an unusable admin address is reported
+		ln, err := net.Listen("tcp", k.Admin.Addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			_ = k.Admin.Serve(ln)
+		}()
+		defer func() {
+			_ = k.Admin.Close()
+		}()
+	}
 	return k.Server.ListenAndServe()
 }
+
+// Shutdown gracefully shuts down the application server and the admin server.
+func (k *Kernel) Shutdown(ctx context.Context) error {
+	if k.Admin == nil {
+		return k.Server.Shutdown(ctx)
+	}
+	return errors.Join(k.Server.Shutdown(ctx), k.Admin.Shutdown(ctx))
+}
@@ -0,0 +1,134 @@
+package mux
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"path"
+	"time"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+)
+
+// AccessLogOption configures the middleware created by [AccessLog].
+type AccessLogOption func(middleware *AccessLogMiddleware)
+
+// AccessLogSampling logs only the given fraction of the requests, between 0 and 1.
+// Failed and slow requests are always logged.
+func AccessLogSampling(rate float64) AccessLogOption {
+	return func(middleware *AccessLogMiddleware) {
+		middleware.sampling = rate
+	}
+}
+
+// AccessLogExcludePaths does not log the requests whose path or route template matches one of the patterns,
+// e.g. "/health" or "/assets/*", see [path.Match].
+func AccessLogExcludePaths(patterns ...string) AccessLogOption {
+	return func(middleware *AccessLogMiddleware) {
+		middleware.excludedPaths = append(middleware.excludedPaths, patterns...)
+	}
+}
+
+// AccessLogSlowThreshold logs the requests which take longer than the threshold as warnings.
+func AccessLogSlowThreshold(threshold time.Duration) AccessLogOption {
+	return func(middleware *AccessLogMiddleware) {
+		middleware.slowThreshold = threshold
+	}
+}
+
+// AccessLogRequestIDHeader sets the request header holding the request ID, which defaults to [DefaultRequestIDHeader].
+// It is only read if the request ID is not in the request context, see [RequestID].
+func AccessLogRequestIDHeader(header string) AccessLogOption {
+	return func(middleware *AccessLogMiddleware) {
+		middleware.requestIDHeader = header
+	}
+}
+
+// AccessLog creates a middleware which logs every request handled by a route as a structured record,
+// with the method, route template, route name, status, bytes written, latency, remote address,
+// user agent and request ID of the request.
+// Requests are logged at the info level, slow requests at the warn level and failed requests at the error level.
+// If the logger is nil, [slog.Default] is used.
+//
+// The record is written once the response has been sent, so it holds the final status and size of the response,
+// even if the handler writes it itself, e.g. routes registered with [Router.Handle] and [Router.Static].
+// Raw routes and requests which match no route are not logged.
+func AccessLog(logger *slog.Logger, options ...AccessLogOption) *AccessLogMiddleware {
+	middleware := &AccessLogMiddleware{
+		logger:          logger,
+		sampling:        1,
+		requestIDHeader: DefaultRequestIDHeader,
+	}
+	for _, option := range options {
+		option(middleware)
+	}
+	return middleware
+}
+
+// AccessLogMiddleware logs requests, see [AccessLog].
+type AccessLogMiddleware struct {
+	logger          *slog.Logger
+	sampling        float64
+	excludedPaths   []string
+	slowThreshold   time.Duration
+	requestIDHeader string
+}
+
+func (a *AccessLogMiddleware) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	return next(request)
+}
+
+func (a *AccessLogMiddleware) Terminate(request *http.Request, _ responseconstract.Responser) {
+	d := dispatchFromContext(request.Context())
+	if d == nil {
+		return
+	}
+	template, _ := d.route.GetPathTemplate()
+	if a.excluded(request.URL.Path, template) {
+		return
+	}
+	latency := d.finished.Sub(d.started)
+	status := d.writer.Status()
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case a.slowThreshold > 0 && latency > a.slowThreshold:
+		level = slog.LevelWarn
+	case a.sampling < 1 && rand.Float64() >= a.sampling:
+		return
+	}
+	logger := a.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	requestID := RequestIDFromContext(request.Context())
+	if requestID == "" {
+		requestID = request.Header.Get(a.requestIDHeader)
+	}
+	logger.LogAttrs(context.WithoutCancel(request.Context()), level, "request",
+		slog.String("method", request.Method),
+		slog.String("route", template),
+		slog.String("name", d.route.GetName()),
+		slog.Int("status", status),
+		slog.Int64("bytes", d.writer.bytes),
+		slog.Duration("latency", latency),
+		slog.String("remote_addr", request.RemoteAddr),
+		slog.String("user_agent", request.UserAgent()),
+		slog.String("request_id", requestID),
+	)
+}
+
+func (a *AccessLogMiddleware) excluded(requestPath, template string) bool {
+	for _, pattern := range a.excludedPaths {
+		if pattern == template {
+			return true
+		}
+		if matched, _ := path.Match(pattern, requestPath); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package mux
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func accessLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	r := New()
+	r.Use(AccessLog(logger, AccessLogExcludePaths("/health", "/assets/*")))
+	r.GET("/users/{id}", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	}).Name("users.show")
+	r.GET("/health", func(request *http.Request) responseconstract.Responser {
+		return nil
+	})
+	r.Handle([]string{"GET"}, "/teapot", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("teapot"))
+	}))
+	r.Static("/assets/", http.FS(fstest.MapFS{"app.css": {Data: []byte("body{}")}}))
+
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("X-Request-Id", "abc")
+	req.RemoteAddr = "127.0.0.1:1234"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	serve(r, "GET", "/health")
+	serve(r, "GET", "/teapot")
+	serve(r, "GET", "/assets/app.css")
+
+	records := accessLogRecords(t, buf)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "INFO", records[0]["level"])
+		assert.Equal(t, "request", records[0]["msg"])
+		assert.Equal(t, "GET", records[0]["method"])
+		assert.Equal(t, "/users/{id}", records[0]["route"])
+		assert.Equal(t, "users.show", records[0]["name"])
+		assert.Equal(t, float64(200), records[0]["status"])
+		assert.Equal(t, float64(5), records[0]["bytes"])
+		assert.Equal(t, "127.0.0.1:1234", records[0]["remote_addr"])
+		assert.Equal(t, "test", records[0]["user_agent"])
+		assert.Equal(t, "abc", records[0]["request_id"])
+		assert.Contains(t, records[0], "latency")
+
+		assert.Equal(t, float64(http.StatusTeapot), records[1]["status"])
+		assert.Equal(t, float64(6), records[1]["bytes"])
+	}
+}
+
+func TestAccessLog_samplingAndThresholds(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	r := New()
+	r.Use(AccessLog(logger, AccessLogSampling(0), AccessLogSlowThreshold(10*time.Millisecond)))
+	r.GET("/fast", func(request *http.Request) responseconstract.Responser {
+		return nil
+	})
+	r.GET("/slow", func(request *http.Request) responseconstract.Responser {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	r.GET("/fail", func(request *http.Request) responseconstract.Responser {
+		return response.New(http.StatusInternalServerError)
+	})
+
+	serve(r, "GET", "/fast")
+	serve(r, "GET", "/slow")
+	serve(r, "GET", "/fail")
+
+	records := accessLogRecords(t, buf)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "/slow", records[0]["route"])
+		assert.Equal(t, "WARN", records[0]["level"])
+		assert.Equal(t, "/fail", records[1]["route"])
+		assert.Equal(t, "ERROR", records[1]["level"])
+	}
+}
@@ -0,0 +1,215 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	"github.com/gopi-frame/exception"
+	"github.com/gorilla/mux"
+)
+
+// ErrModelNotFound is returned by resolvers when no model matches the value of a path parameter.
+// Unless the error handler handles it, the request is answered with 404 Not Found.
+var ErrModelNotFound = errors.New("mux: model not found")
+
+// binding resolves the value of a path parameter into a model.
+type binding func(request *http.Request, value string) (any, error)
+
+// Bind registers the resolver of the path parameter for the router and all its groups.
+// The model it returns is available to the handler with [Model],
+// and to the `bind:"param"` tagged fields of constructable controllers.
+//
+// Parameters are resolved after the middlewares of the route ran, before the handler,
+// in the order they appear in the route, so a resolver can use the models of the parameters before it.
+// If a resolver fails, the request is aborted with the response of the error handler, see [Router.OnError].
+func Bind[T any](r *Router, param string, resolver func(request *http.Request, value string) (T, error)) {
+	if param == "" {
+		panic(exception.NewArgumentException("param", param, "bound parameter should not be empty"))
+	}
+	r.bindingsMu.Lock()
+	defer r.bindingsMu.Unlock()
+	if r.bindings == nil {
+		r.bindings = make(map[string]binding)
+	}
+	r.bindings[param] = func(request *http.Request, value string) (any, error) {
+		return resolver(request, value)
+	}
+}
+
+// BindType registers the resolver of the path parameters named after the type T,
+// which is the name of the type starting with a lower case letter, e.g. {user} for User or *User.
+func BindType[T any](r *Router, resolver func(request *http.Request, value string) (T, error)) {
+	Bind(r, typeParam(reflect.TypeFor[T]()), resolver)
+}
+
+// BindScoped registers the resolver of the path parameter which is scoped to the model of the parent parameter,
+// e.g. {post} to {user} in /users/{user}/posts/{post}.
+// The resolver should return [ErrModelNotFound] if the model does not belong to the parent model.
+func BindScoped[T, P any](r *Router, param, parent string, resolver func(request *http.Request, parent P, value string) (T, error)) {
+	Bind(r, param, func(request *http.Request, value string) (T, error) {
+		model, ok := Model[P](request, parent)
+		if !ok {
+			var zero T
+			return zero, fmt.Errorf("mux: parameter %s is scoped to parameter %s which has no %s model", param, parent, reflect.TypeFor[P]())
+		}
+		return resolver(request, model, value)
+	})
+}
+
+// Model returns the model of type T resolved from the path parameter of the request.
+func Model[T any](request *http.Request, param string) (T, bool) {
+	var zero T
+	d := dispatchFromContext(request.Context())
+	if d == nil {
+		return zero, false
+	}
+	model, ok := d.models[param].(T)
+	return model, ok
+}
+
+// binding returns the resolver of the path parameter registered on the router or the nearest of its parents.
+func (r *Router) binding(param string) binding {
+	for ; r != nil; r = r.parent {
+		r.bindingsMu.RLock()
+		b, ok := r.bindings[param]
+		r.bindingsMu.RUnlock()
+		if ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// resolveModels resolves the path parameters of the request which have a binding into the models of the dispatch.
+// It reports whether the request should be aborted, with the response to abort it with.
+func (r *Route) resolveModels(request *http.Request, d *dispatch) (responseconstract.Responser, bool) {
+	names, err := r.GetVarNames()
+	if err != nil {
+		return nil, false
+	}
+	vars := mux.Vars(request)
+	for _, name := range names {
+		value, ok := vars[name]
+		if !ok {
+			continue
+		}
+		resolve := r.router.binding(name)
+		if resolve == nil {
+			continue
+		}
+		model, err := resolve(request, value)
+		if err != nil {
+			return errorResponse(request, err), true
+		}
+		if d.models == nil {
+			d.models = make(map[string]any)
+		}
+		d.models[name] = model
+	}
+	return nil, false
+}
+
+// typeParam returns the name of the type starting with a lower case letter.
+// It panics if the type is unnamed.
+func typeParam(t reflect.Type) string {
+	name := lowerTypeName(t)
+	if name == "" {
+		panic(exception.NewArgumentException("type", t.String(), "bound type should be named"))
+	}
+	return name
+}
+
+// lowerTypeName returns the name of the type starting with a lower case letter, dereferencing pointers,
+// or an empty string if the type is unnamed.
+func lowerTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name := t.Name()
+	if name == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
+
+type boundField struct {
+	index int
+	param string
+}
+
+// boundFieldsCache caches the fields tagged with `bind` of struct types.
+var boundFieldsCache sync.Map
+
+// boundFields returns the fields tagged with `bind` of the struct type.
+// The parameter of a field with an empty tag is named after the type of the field, see [BindType].
+// It fails if a field is unexported, or has an empty tag and an unnamed type.
+func boundFields(t reflect.Type) ([]boundField, error) {
+	if fields, ok := boundFieldsCache.Load(t); ok {
+		return fields.([]boundField), nil
+	}
+	var fields []boundField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		param, ok := field.Tag.Lookup("bind")
+		if !ok {
+			continue
+		}
+		if !field.IsExported() {
+			return nil, exception.NewArgumentException(t.String()+"."+field.Name, field.Type.String(), "bound field should be exported")
+		}
+		if param == "" {
+			if param = lowerTypeName(field.Type); param == "" {
+				return nil, exception.NewArgumentException(t.String()+"."+field.Name, field.Type.String(), "bound field of an unnamed type should name its parameter")
+			}
+		}
+		fields = append(fields, boundField{index: i, param: param})
+	}
+	boundFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+// checkBoundFields reports the invalid fields tagged with `bind` of the type, see [boundFields].
+func checkBoundFields(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	_, err := boundFields(t)
+	return err
+}
+
+// bindModels sets the fields tagged with `bind` of the instance, which is a pointer to a struct,
+// to the models resolved for the request.
+func bindModels(request *http.Request, instance reflect.Value) error {
+	d := dispatchFromContext(request.Context())
+	elem := instance.Elem()
+	if d == nil || len(d.models) == 0 || elem.Kind() != reflect.Struct {
+		return nil
+	}
+	fields, err := boundFields(elem.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		model, ok := d.models[f.param]
+		if !ok || model == nil {
+			continue
+		}
+		field := elem.Field(f.index)
+		value := reflect.ValueOf(model)
+		if !value.Type().AssignableTo(field.Type()) {
+			return exception.NewArgumentException(elem.Type().String()+"."+elem.Type().Field(f.index).Name, value.Type().String(), "model is not assignable to bound field")
+		}
+		field.Set(value)
+	}
+	return nil
+}
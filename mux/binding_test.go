@@ -0,0 +1,174 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	ID   string
+	Name string
+}
+
+type post struct {
+	ID     string
+	UserID string
+}
+
+var (
+	users = map[string]*User{"1": {ID: "1", Name: "alice"}, "2": {ID: "2", Name: "bob"}}
+	posts = map[string]*post{"10": {ID: "10", UserID: "1"}, "20": {ID: "20", UserID: "2"}}
+)
+
+func findUser(r *http.Request, id string) (*User, error) {
+	if user, ok := users[id]; ok {
+		return user, nil
+	}
+	return nil, ErrModelNotFound
+}
+
+type userController struct {
+	User *User `bind:""`
+	Post *post `bind:"post"`
+}
+
+func (u *userController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/users"}
+}
+
+func (u *userController) Construct(r *http.Request) {}
+
+func (u *userController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, u.User.Name)
+}
+
+func (u *userController) ShowPost(r *http.Request) responseconstract.Responser {
+	return response.New(200, u.User.Name+" "+u.Post.ID)
+}
+
+func serve(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	r.ServeHTTP(w, req)
+	// terminable middlewares run after the response has been sent
+	terminations.Wait()
+	return w
+}
+
+func TestBind(t *testing.T) {
+	r := New()
+	Bind(r, "user", findUser)
+	r.GET("/users/{user}", func(request *http.Request) responseconstract.Responser {
+		user, ok := Model[*User](request, "user")
+		assert.True(t, ok)
+		return response.New(200, user.Name)
+	})
+	r.GET("/profiles/{id}", func(request *http.Request) responseconstract.Responser {
+		_, ok := Model[*User](request, "id")
+		assert.False(t, ok)
+		return response.New(200, "unbound")
+	})
+
+	w := serve(r, "GET", "/users/1")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+
+	w = serve(r, "GET", "/users/3")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(r, "GET", "/profiles/3")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "unbound", w.Body.String())
+
+	assert.Panics(t, func() {
+		Bind(r, "", findUser)
+	})
+}
+
+func TestBind_group(t *testing.T) {
+	r := New()
+	Bind(r, "user", findUser)
+	r.Group(&RouteGroup{Prefix: "/admin"}, func(router routercontract.Router) {
+		Bind(router.(*Router), "user", func(request *http.Request, value string) (*User, error) {
+			return &User{ID: value, Name: "admin " + value}, nil
+		})
+		router.GET("/users/{user}", func(request *http.Request) responseconstract.Responser {
+			user, _ := Model[*User](request, "user")
+			return response.New(200, user.Name)
+		})
+	})
+	r.GET("/users/{user}", func(request *http.Request) responseconstract.Responser {
+		user, _ := Model[*User](request, "user")
+		return response.New(200, user.Name)
+	})
+
+	assert.Equal(t, "admin 3", serve(r, "GET", "/admin/users/3").Body.String())
+	assert.Equal(t, http.StatusNotFound, serve(r, "GET", "/users/3").Code)
+}
+
+func TestBindScoped(t *testing.T) {
+	r := New()
+	r.OnError(func(request *http.Request, err error) responseconstract.Responser {
+		return response.New(http.StatusNotFound, err.Error())
+	})
+	BindType(r, findUser)
+	BindScoped(r, "post", "user", func(request *http.Request, user *User, value string) (*post, error) {
+		if p, ok := posts[value]; ok && p.UserID == user.ID {
+			return p, nil
+		}
+		return nil, ErrModelNotFound
+	})
+	r.Controller(new(userController), func(router routercontract.Router) {
+		router.GET("/{user}", Action((*userController).Show))
+		router.GET("/{user}/posts/{post}", Action((*userController).ShowPost))
+	})
+
+	for _, c := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/2", 200, "bob"},
+		{"/users/1/posts/10", 200, "alice 10"},
+		{"/users/1/posts/20", http.StatusNotFound, ErrModelNotFound.Error()},
+		{"/users/3/posts/10", http.StatusNotFound, ErrModelNotFound.Error()},
+	} {
+		w := serve(r, "GET", c.path)
+		assert.Equal(t, c.code, w.Code, c.path)
+		assert.Equal(t, c.body, w.Body.String(), c.path)
+	}
+}
+
+type unexportedBindingController struct {
+	user *User `bind:""`
+}
+
+func (u *unexportedBindingController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/unexported"}
+}
+
+func (u *unexportedBindingController) Construct(r *http.Request) {}
+
+func (u *unexportedBindingController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, u.user.Name)
+}
+
+func TestBind_invalidField(t *testing.T) {
+	r := New()
+	BindType(r, findUser)
+	r.Controller(new(unexportedBindingController), func(router routercontract.Router) {
+		RouteAction(router, []string{http.MethodGet}, "/{user}", (*unexportedBindingController).Show)
+	})
+	err := r.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/unexported/{user}")
+		assert.Contains(t, err.Error(), "bound field should be exported")
+	}
+	assert.Equal(t, 500, serve(r, "GET", "/unexported/1").Code)
+}
@@ -0,0 +1,96 @@
+package mux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+const concurrentRequests = 64
+
+func serveConcurrently(t *testing.T, r http.Handler, method string, target func(i int) string, check func(t *testing.T, i int, w *httptest.ResponseRecorder)) {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(method, target(i), nil)
+			r.ServeHTTP(w, req)
+			check(t, i, w)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRouter_Concurrency(t *testing.T) {
+	t.Run("global constructable middleware", func(t *testing.T) {
+		r := New()
+		r.Use(new(nonStaticMiddleware))
+		r.GET("/get", func(request *http.Request) responseconstract.Responser {
+			return response.New(200).JSON(map[string]any{"id": request.Context().Value(idKey)})
+		})
+		serveConcurrently(t, r, "GET", func(i int) string {
+			return fmt.Sprintf("/get?id=%d", i)
+		}, func(t *testing.T, i int, w *httptest.ResponseRecorder) {
+			assert.Equal(t, 200, w.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"id": "%d"}`, i), w.Body.String())
+			assert.Equal(t, "GET", w.Header().Get("Request-Method"))
+		})
+	})
+
+	t.Run("route constructable middleware", func(t *testing.T) {
+		r := New()
+		r.GET("/get", func(request *http.Request) responseconstract.Responser {
+			return response.New(200).JSON(map[string]any{"id": request.Context().Value(idKey)})
+		}).Use(new(nonStaticMiddleware))
+		serveConcurrently(t, r, "GET", func(i int) string {
+			return fmt.Sprintf("/get?id=%d", i)
+		}, func(t *testing.T, i int, w *httptest.ResponseRecorder) {
+			assert.Equal(t, 200, w.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"id": "%d"}`, i), w.Body.String())
+			assert.Equal(t, "GET", w.Header().Get("Request-Method"))
+		})
+	})
+
+	t.Run("group constructable middleware", func(t *testing.T) {
+		r := New()
+		r.Group(&RouteGroup{Prefix: "/group"}, func(router routercontract.Router) {
+			router.Use(new(nonStaticMiddleware))
+			router.POST("/post", func(request *http.Request) responseconstract.Responser {
+				return response.New(200).JSON(map[string]any{"id": request.Context().Value(idKey)})
+			})
+		})
+		serveConcurrently(t, r, "POST", func(i int) string {
+			return fmt.Sprintf("/group/post?id=%d", i)
+		}, func(t *testing.T, i int, w *httptest.ResponseRecorder) {
+			assert.Equal(t, 200, w.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"id": "%d"}`, i), w.Body.String())
+			assert.Equal(t, "POST", w.Header().Get("Request-Method"))
+		})
+	})
+
+	t.Run("constructable controller", func(t *testing.T) {
+		controller := new(nonStaticController)
+		controller.Prefix = "/prefix"
+		r := New()
+		r.Controller(controller, func(router routercontract.Router) {
+			router.Use(new(nonStaticMiddleware))
+			router.GET("/get", controller.Get)
+		})
+		serveConcurrently(t, r, "GET", func(i int) string {
+			return fmt.Sprintf("/prefix/get?id=%d", i)
+		}, func(t *testing.T, i int, w *httptest.ResponseRecorder) {
+			assert.Equal(t, 200, w.Code)
+			assert.JSONEq(t, fmt.Sprintf(`{"id": ["%d"]}`, i), w.Body.String())
+			assert.Equal(t, "GET", w.Header().Get("Request-Method"))
+		})
+	})
+}
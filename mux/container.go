@@ -0,0 +1,327 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/exception"
+)
+
+// Lifetime is the lifetime of a service registered in a [Container].
+type Lifetime int
+
+const (
+	// LifetimeSingleton services are created once and shared by every request.
+	LifetimeSingleton Lifetime = iota
+	// LifetimeScoped services are created once per request and closed when the request ends.
+	LifetimeScoped
+)
+
+// Container holds the services which can be injected into constructable controllers and middlewares.
+//
+// For every request handled by a router with a container,
+// a [Scope] is created and stored in the request context, and closed when the request ends.
+// Services are injected into the exported fields tagged with `inject:""` of each new controller and middleware instance,
+// before Construct is called, and can be resolved in Construct with [Resolve] and [ScopeFromContext].
+type Container struct {
+	mu        sync.RWMutex
+	providers map[reflect.Type]*provider
+	fields    map[reflect.Type][]int
+	closers   []io.Closer
+	// root is the scope singleton services are created in, which has no request and no scoped services.
+	root *Scope
+}
+
+type provider struct {
+	lifetime Lifetime
+	factory  func(scope *Scope) (any, error)
+
+	once  sync.Once
+	value any
+	err   error
+}
+
+func NewContainer() *Container {
+	c := &Container{
+		providers: make(map[reflect.Type]*provider),
+		fields:    make(map[reflect.Type][]int),
+	}
+	c.root = &Scope{container: c, root: true}
+	return c
+}
+
+// Singleton registers a service of type T created once by the factory.
+// The factory gets the root scope of the container, which has no request and can't resolve scoped services,
+// since they would be closed when the request which created the singleton ends.
+// If the service implements [io.Closer], it is closed by [Container.Close].
+func Singleton[T any](c *Container, factory func(scope *Scope) (T, error)) {
+	c.provide(reflect.TypeFor[T](), LifetimeSingleton, func(scope *Scope) (any, error) {
+		return factory(scope)
+	})
+}
+
+// Scoped registers a service of type T created once per request by the factory.
+// If the service implements [io.Closer], it is closed when the request ends.
+func Scoped[T any](c *Container, factory func(scope *Scope) (T, error)) {
+	c.provide(reflect.TypeFor[T](), LifetimeScoped, func(scope *Scope) (any, error) {
+		return factory(scope)
+	})
+}
+
+// Instance registers the value as a singleton service of type T.
+func Instance[T any](c *Container, value T) {
+	Singleton(c, func(*Scope) (T, error) {
+		return value, nil
+	})
+}
+
+// Resolve returns the service of type T for the scope.
+func Resolve[T any](scope *Scope) (T, error) {
+	var zero T
+	if scope == nil {
+		return zero, errors.New("mux: no service scope, the router has no container")
+	}
+	value, err := scope.resolve(reflect.TypeFor[T]())
+	if err != nil {
+		return zero, err
+	}
+	service, ok := value.(T)
+	if !ok {
+		return zero, exception.NewArgumentException("service", reflect.TypeFor[T]().String(), "service factory returned nil")
+	}
+	return service, nil
+}
+
+func (c *Container) provide(t reflect.Type, lifetime Lifetime, factory func(scope *Scope) (any, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers[t] = &provider{lifetime: lifetime, factory: factory}
+}
+
+// NewScope creates a scope for the request.
+// The caller should close it when the request ends.
+func (c *Container) NewScope(request *http.Request) *Scope {
+	return &Scope{
+		container: c,
+		request:   request,
+		instances: make(map[reflect.Type]any),
+	}
+}
+
+// Close closes every singleton service which implements [io.Closer], in reverse order of creation.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+	return closeAll(closers)
+}
+
+// injectableFields returns the indexes of the fields tagged with `inject` of the struct type.
+func (c *Container) injectableFields(t reflect.Type) ([]int, error) {
+	c.mu.RLock()
+	fields, ok := c.fields[t]
+	c.mu.RUnlock()
+	if ok {
+		return fields, nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, ok := field.Tag.Lookup("inject"); !ok {
+			continue
+		}
+		if !field.IsExported() {
+			return nil, exception.NewArgumentException(t.String()+"."+field.Name, field.Type.String(), "injected field should be exported")
+		}
+		fields = append(fields, i)
+	}
+	c.mu.Lock()
+	c.fields[t] = fields
+	c.mu.Unlock()
+	return fields, nil
+}
+
+// check reports the fields tagged with `inject` of the type, a struct or a pointer to a struct,
+// which are unexported or whose service is not registered.
+func (c *Container) check(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields, err := c.injectableFields(t)
+	if err != nil {
+		return err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, i := range fields {
+		field := t.Field(i)
+		if _, ok := c.providers[field.Type]; !ok {
+			return exception.NewArgumentException(t.String()+"."+field.Name, field.Type.String(), "injected service is not registered")
+		}
+	}
+	return nil
+}
+
+// checkFields checks the fields of the controller and the constructable middlewares of the route:
+// the models bound, see [checkBoundFields], and the services injected if there is a container, see [Container.check].
+func (r *Route) checkFields(c *Container, middlewares []routercontract.Middleware) error {
+	var types []reflect.Type
+	if controller := r.controller; controller != nil {
+		types = append(types, controller)
+	} else if controller = r.router.controller(); controller != nil {
+		types = append(types, controller)
+	}
+	for _, middleware := range middlewares {
+		if am, ok := middleware.(*aliasedMiddleware); ok {
+			middleware = am.Middleware
+		}
+		switch middleware.(type) {
+		case ParameterizedMiddleware, routercontract.ConstructableMiddleware, FallibleMiddleware, ShortCircuitMiddleware:
+			types = append(types, reflect.TypeOf(middleware))
+		}
+	}
+	var errs []error
+	for _, t := range types {
+		errs = append(errs, checkBoundFields(t))
+		if c != nil {
+			errs = append(errs, c.check(t))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Scope holds the scoped services of a request.
+type Scope struct {
+	container *Container
+	request   *http.Request
+
+	mu        sync.Mutex
+	instances map[reflect.Type]any
+	closers   []io.Closer
+	// root reports whether the scope is the root scope of the container, see [Singleton].
+	root bool
+}
+
+type scopeKey struct{}
+
+// ScopeFromContext returns the scope stored in the context, or nil if there is none.
+func ScopeFromContext(ctx context.Context) *Scope {
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+// WithContext returns a copy of the context which holds the scope.
+func (s *Scope) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// Request returns the request the scope has been created for.
+func (s *Scope) Request() *http.Request {
+	return s.request
+}
+
+func (s *Scope) resolve(t reflect.Type) (any, error) {
+	s.container.mu.RLock()
+	p, ok := s.container.providers[t]
+	s.container.mu.RUnlock()
+	if !ok {
+		return nil, exception.NewArgumentException("service", t.String(), "service is not registered")
+	}
+	if p.lifetime == LifetimeSingleton {
+		p.once.Do(func() {
+			p.value, p.err = p.factory(s.container.root)
+			if closer, ok := p.value.(io.Closer); ok && p.err == nil {
+				s.container.mu.Lock()
+				s.container.closers = append(s.container.closers, closer)
+				s.container.mu.Unlock()
+			}
+		})
+		return p.value, p.err
+	}
+	if s.root {
+		return nil, exception.NewArgumentException("service", t.String(), "scoped service can't be resolved by a singleton service")
+	}
+	s.mu.Lock()
+	value, ok := s.instances[t]
+	s.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+	// the factory runs unlocked, since it may resolve other services from the scope
+	value, err := p.factory(s)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.instances[t]; ok {
+		return existing, nil
+	}
+	s.instances[t] = value
+	if closer, ok := value.(io.Closer); ok {
+		s.closers = append(s.closers, closer)
+	}
+	return value, nil
+}
+
+// inject sets the fields tagged with `inject` of the instance, which is a pointer to a struct.
+func (s *Scope) inject(instance reflect.Value) error {
+	elem := instance.Elem()
+	if elem.Kind() != reflect.Struct {
+		return nil
+	}
+	fields, err := s.container.injectableFields(elem.Type())
+	if err != nil {
+		return err
+	}
+	for _, i := range fields {
+		field := elem.Field(i)
+		value, err := s.resolve(field.Type())
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			field.Set(reflect.ValueOf(value))
+		}
+	}
+	return nil
+}
+
+// Close closes every scoped service which implements [io.Closer], in reverse order of creation.
+func (s *Scope) Close() error {
+	s.mu.Lock()
+	closers := s.closers
+	s.closers = nil
+	s.mu.Unlock()
+	return closeAll(closers)
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// injectScope injects services from the scope of the request into the instance, if there is a scope,
+// and the models resolved for the request, see [Bind].
+// The request should be aborted with the response of the error handler if it fails, see [Router.OnError].
+func injectScope(request *http.Request, instance reflect.Value) error {
+	if scope := ScopeFromContext(request.Context()); scope != nil {
+		if err := scope.inject(instance); err != nil {
+			return err
+		}
+	}
+	return bindModels(request, instance)
+}
@@ -0,0 +1,187 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type config struct {
+	Name string
+}
+
+type session struct {
+	ID     int
+	closed bool
+}
+
+func (s *session) Close() error {
+	s.closed = true
+	return nil
+}
+
+type injectedMiddleware struct {
+	Session *session `inject:""`
+}
+
+func (i *injectedMiddleware) Construct(r *http.Request) {
+	r.Header.Set("Middleware-Session", strconv.Itoa(i.Session.ID))
+}
+
+func (i *injectedMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	return next(r)
+}
+
+type injectedController struct {
+	Config  *config  `inject:""`
+	Session *session `inject:""`
+	method  string
+}
+
+func (i *injectedController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/injected"}
+}
+
+func (i *injectedController) Construct(r *http.Request) {
+	s, err := Resolve[*session](ScopeFromContext(r.Context()))
+	if err != nil || s != i.Session {
+		panic("session should be resolved from the request scope")
+	}
+	i.method = r.Method
+}
+
+func (i *injectedController) Get(r *http.Request) responseconstract.Responser {
+	return response.New(200).JSON(map[string]any{
+		"name":       i.Config.Name,
+		"session":    i.Session.ID,
+		"middleware": r.Header.Get("Middleware-Session"),
+		"method":     i.method,
+	})
+}
+
+func TestContainer(t *testing.T) {
+	var sessions []*session
+	var ids atomic.Int64
+	container := NewContainer()
+	Instance(container, &config{Name: "app"})
+	Scoped(container, func(scope *Scope) (*session, error) {
+		s := &session{ID: int(ids.Add(1))}
+		sessions = append(sessions, s)
+		return s, nil
+	})
+
+	r := New().SetContainer(container)
+	r.Use(new(injectedMiddleware))
+	controller := new(injectedController)
+	r.Controller(controller, func(router routercontract.Router) {
+		router.GET("/get", controller.Get)
+	})
+
+	for i := 1; i <= 2; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/injected/get", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{"name": "app", "session": `+strconv.Itoa(i)+`, "middleware": "`+strconv.Itoa(i)+`", "method": "GET"}`, w.Body.String())
+	}
+	if assert.Len(t, sessions, 2) {
+		assert.True(t, sessions[0].closed)
+		assert.True(t, sessions[1].closed)
+	}
+
+	t.Run("not registered", func(t *testing.T) {
+		scope := NewContainer().NewScope(nil)
+		_, err := Resolve[*config](scope)
+		assert.Error(t, err)
+		_, err = Resolve[*config](nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("singleton close", func(t *testing.T) {
+		c := NewContainer()
+		Singleton(c, func(scope *Scope) (*session, error) {
+			return &session{}, nil
+		})
+		s, err := Resolve[*session](c.NewScope(nil))
+		assert.NoError(t, err)
+		s2, err := Resolve[*session](c.NewScope(nil))
+		assert.NoError(t, err)
+		assert.Same(t, s, s2)
+		assert.NoError(t, c.Close())
+		assert.True(t, s.closed)
+	})
+}
+
+type failingService struct{}
+
+type clock interface {
+	Now() int
+}
+
+func TestContainer_errors(t *testing.T) {
+	t.Run("injection error", func(t *testing.T) {
+		container := NewContainer()
+		Instance(container, &config{Name: "app"})
+		Scoped(container, func(scope *Scope) (*session, error) {
+			return nil, errors.New("no session")
+		})
+		r := New().SetContainer(container)
+		r.OnError(func(request *http.Request, err error) responseconstract.Responser {
+			return response.New(http.StatusServiceUnavailable, err.Error())
+		})
+		r.GET("/get", Action((*injectedController).Get))
+		r.GET("/middleware", func(request *http.Request) responseconstract.Responser {
+			return response.New(200)
+		}).Use(new(injectedMiddleware))
+
+		for _, path := range []string{"/get", "/middleware"} {
+			w := serve(r, "GET", path)
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+			assert.Equal(t, "no session", w.Body.String(), path)
+		}
+	})
+
+	t.Run("unregistered service", func(t *testing.T) {
+		r := New().SetContainer(NewContainer())
+		RouteAction(r, []string{http.MethodGet}, "/get", (*injectedController).Get)
+		err := r.Validate()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "injected service is not registered")
+		}
+		assert.Equal(t, http.StatusInternalServerError, serve(r, "GET", "/get").Code)
+	})
+
+	t.Run("nil interface service", func(t *testing.T) {
+		c := NewContainer()
+		Singleton(c, func(scope *Scope) (clock, error) {
+			return nil, nil
+		})
+		_, err := Resolve[clock](c.NewScope(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("scoped service in singleton", func(t *testing.T) {
+		c := NewContainer()
+		Scoped(c, func(scope *Scope) (*session, error) {
+			return &session{}, nil
+		})
+		Singleton(c, func(scope *Scope) (*failingService, error) {
+			assert.Nil(t, scope.Request())
+			_, err := Resolve[*session](scope)
+			return nil, err
+		})
+		req, _ := http.NewRequest("GET", "/", nil)
+		_, err := Resolve[*failingService](c.NewScope(req))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "scoped service can't be resolved by a singleton service")
+		}
+	})
+}
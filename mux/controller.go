@@ -0,0 +1,411 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/exception"
+	"github.com/gopi-frame/pipeline"
+)
+
+// FallibleController is a constructable controller whose construction can fail.
+// If Construct returns an error, the request is aborted with the response of the error handler,
+// see [Router.OnError].
+type FallibleController interface {
+	routercontract.Controller
+	Construct(request *http.Request) error
+}
+
+// ShortCircuitController is a constructable controller whose construction can end the request.
+// If Construct returns a response, the request is aborted with it.
+type ShortCircuitController interface {
+	routercontract.Controller
+	Construct(request *http.Request) responseconstract.Responser
+}
+
+var (
+	requestType   = reflect.TypeFor[*http.Request]()
+	errorType     = reflect.TypeFor[error]()
+	responserType = reflect.TypeFor[responseconstract.Responser]()
+)
+
+// constructMethod returns the Construct method of the controller type, if it has one with a supported signature:
+// Construct(*http.Request), Construct(*http.Request) error or Construct(*http.Request) Responser.
+func constructMethod(controllerType reflect.Type) (reflect.Method, bool) {
+	method, ok := controllerType.MethodByName("Construct")
+	if !ok || method.Type.NumIn() != 2 || method.Type.In(1) != requestType {
+		return method, false
+	}
+	switch method.Type.NumOut() {
+	case 0:
+		return method, true
+	case 1:
+		return method, method.Type.Out(0) == errorType || method.Type.Out(0) == responserType
+	}
+	return method, false
+}
+
+// Action binds a controller method to a handler, given as a method expression, e.g. (*UserController).Show.
+// For every request, a new instance of the controller is created,
+// gets services injected from the scope of the request if there is one,
+// is constructed with the request if it has a Construct method, see [constructMethod],
+// then the method is called on it, unless the construction aborted the request.
+// Register the route with [RouteAction] instead to record the action of the route, see [RouteFromContext].
+//
+// Action replaces binding method values of constructable controllers, e.g. controller.Show,
+// which are recognized by the name of the function and are deprecated.
+func Action[C any](method func(C, *http.Request) responseconstract.Responser) routercontract.Handler {
+	handler, _ := action(method)
+	return handler
+}
+
+// RouteAction registers a route of the router for the controller method given as a method expression,
+// which handles requests like with [Action], and records the controller and the action of the route,
+// see [RouteFromContext].
+func RouteAction[C any](r routercontract.Router, methods []string, path string, method func(C, *http.Request) responseconstract.Responser) *Route {
+	handler, name := action(method)
+	route := r.Route(methods, path, handler).(*Route)
+	route.controller, route.action = reflect.TypeFor[C](), name
+	return route
+}
+
+// action returns the handler of the controller method given as a method expression, and the name of the method.
+func action[C any](method func(C, *http.Request) responseconstract.Responser) (routercontract.Handler, string) {
+	cType := reflect.TypeFor[C]()
+	if cType.Kind() != reflect.Pointer || cType.Elem().Kind() != reflect.Struct {
+		panic(exception.NewArgumentException("controller", cType.String(), "controller should be a pointer to a struct"))
+	}
+	name, ok := actionName(cType, method)
+	if !ok {
+		panic(exception.NewArgumentException("method", cType.String(), "method should be a method expression of the controller, e.g. (*UserController).Show"))
+	}
+	construct, isConstructable := constructMethod(cType)
+	return func(request *http.Request) responseconstract.Responser {
+		instance := reflect.New(cType.Elem())
+		if err := injectScope(request, instance); err != nil {
+			return errorResponse(request, err)
+		}
+		if isConstructable {
+			if resp, aborted := callConstruct(request, instance.Method(construct.Index), reflect.ValueOf(request)); aborted {
+				return resp
+			}
+		}
+		controller := instance.Interface().(C)
+		return invokeAction(controller, name, request, func(request *http.Request) responseconstract.Responser {
+			return method(controller, request)
+		})
+	}, name
+}
+
+var handlerType = reflect.TypeFor[routercontract.Handler]()
+
+// actionHandler returns the handler of the named method of the controller.
+// If the controller has a Construct method, see [constructMethod], the handler calls the method on a new instance
+// for every request, like with [Action], otherwise it calls the method on the controller itself.
+func actionHandler(controller any, name string) (routercontract.Handler, error) {
+	controllerType := reflect.TypeOf(controller)
+	method, ok := controllerType.MethodByName(name)
+	if !ok {
+		return nil, exception.NewArgumentException("action", name, fmt.Sprintf("controller %s has no method %s", controllerType, name))
+	}
+	// the signature without the receiver
+	in := make([]reflect.Type, 0, method.Type.NumIn()-1)
+	for i := 1; i < method.Type.NumIn(); i++ {
+		in = append(in, method.Type.In(i))
+	}
+	out := make([]reflect.Type, 0, method.Type.NumOut())
+	for i := 0; i < method.Type.NumOut(); i++ {
+		out = append(out, method.Type.Out(i))
+	}
+	if !reflect.FuncOf(in, out, method.Type.IsVariadic()).ConvertibleTo(handlerType) {
+		return nil, exception.NewArgumentException("action", name, fmt.Sprintf("controller method %s.%s should have the signature func(*http.Request) Responser", controllerType, name))
+	}
+	construct, isConstructable := constructMethod(controllerType)
+	if !isConstructable {
+		bound := reflect.ValueOf(controller).Method(method.Index).Convert(handlerType).Interface().(routercontract.Handler)
+		return func(request *http.Request) responseconstract.Responser {
+			return invokeAction(controller, name, request, bound)
+		}, nil
+	}
+	if controllerType.Kind() != reflect.Pointer || controllerType.Elem().Kind() != reflect.Struct {
+		return nil, exception.NewArgumentException("controller", controller, fmt.Sprintf("controller %s should be a pointer to a struct", controllerType))
+	}
+	return func(request *http.Request) responseconstract.Responser {
+		instance := reflect.New(controllerType.Elem())
+		if err := injectScope(request, instance); err != nil {
+			return errorResponse(request, err)
+		}
+		if resp, aborted := callConstruct(request, instance.Method(construct.Index), reflect.ValueOf(request)); aborted {
+			return resp
+		}
+		return invokeAction(instance.Interface(), name, request, func(request *http.Request) responseconstract.Responser {
+			out := instance.Method(method.Index).Call([]reflect.Value{reflect.ValueOf(request)})
+			resp, _ := out[0].Interface().(responseconstract.Responser)
+			return resp
+		})
+	}, nil
+}
+
+// RouteDefinition declares a route for an action of a controller.
+type RouteDefinition struct {
+	// Methods are the HTTP methods of the route, e.g. GET.
+	Methods []string
+	// Path is the path of the route, relative to the route group of the controller.
+	Path string
+	// Action is the name of the controller method, with the signature func(*http.Request) Responser.
+	Action string
+	// Name is the name of the route, if any.
+	Name string
+	// Middlewares are appended to the route.
+	Middlewares []routercontract.Middleware
+}
+
+// RouteDeclarer is implemented by controllers which declare their routes.
+type RouteDeclarer interface {
+	Routes() []RouteDefinition
+}
+
+// Endpoint is the type of controller fields which declare a route with struct tags:
+//
+//	route       HTTP methods separated with commas, in any case, a space, then the path, e.g. "GET, HEAD /{id}"
+//	action      name of the controller method, defaults to the name of the field without the "Route" suffix
+//	name        name of the route
+//	middleware  names of middlewares separated with spaces, see [Named],
+//	            which should be registered before the controller
+//
+// For example:
+//
+//	type UserController struct {
+//		ShowRoute mux.Endpoint `route:"GET /{id}" name:"users.show" middleware:"auth throttle:60,1"`
+//	}
+type Endpoint struct{}
+
+var endpointType = reflect.TypeFor[Endpoint]()
+
+// declaredRoutes returns the routes declared by the controller, with its Routes method then with struct tags.
+func declaredRoutes(controller any) ([]RouteDefinition, error) {
+	var definitions []RouteDefinition
+	if rd, ok := controller.(RouteDeclarer); ok {
+		definitions = append(definitions, rd.Routes()...)
+	}
+	t := reflect.TypeOf(controller)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return definitions, nil
+	}
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type != endpointType {
+			continue
+		}
+		// the path follows the last space, since the methods may be separated with spaces too
+		tag := strings.TrimSpace(field.Tag.Get("route"))
+		i := strings.LastIndex(tag, " ")
+		if i < 0 {
+			errs = append(errs, exception.NewArgumentException("route", tag, fmt.Sprintf("route tag of %s.%s should be methods followed by a path, e.g. \"GET /{id}\"", t, field.Name)))
+			continue
+		}
+		var methods []string
+		for _, method := range strings.Split(tag[:i], ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				methods = append(methods, method)
+			}
+		}
+		definition := RouteDefinition{
+			Methods: methods,
+			Path:    tag[i+1:],
+			Action:  field.Tag.Get("action"),
+			Name:    field.Tag.Get("name"),
+		}
+		if definition.Action == "" {
+			definition.Action = strings.TrimSuffix(field.Name, "Route")
+		}
+		for _, name := range strings.Fields(field.Tag.Get("middleware")) {
+			definition.Middlewares = append(definition.Middlewares, Named(name))
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, errors.Join(errs...)
+}
+
+// registerDeclaredRoutes registers the routes declared by the controller on the router.
+// Every invalid definition is reported in the returned error, and no route is registered in that case.
+func registerDeclaredRoutes(r *Router, controller any) error {
+	definitions, err := declaredRoutes(controller)
+	errs := []error{err}
+	handlers := make([]routercontract.Handler, len(definitions))
+	for i, definition := range definitions {
+		for _, method := range definition.Methods {
+			if !isHTTPMethod(strings.ToUpper(method)) {
+				errs = append(errs, exception.NewArgumentException("method", method, fmt.Sprintf("unknown HTTP method %q for action %s", method, definition.Action)))
+			}
+		}
+		if len(definition.Methods) == 0 {
+			errs = append(errs, exception.NewArgumentException("method", definition.Methods, fmt.Sprintf("no HTTP method for action %s", definition.Action)))
+		}
+		for _, middleware := range definition.Middlewares {
+			if n, ok := middleware.(*namedMiddleware); ok {
+				if _, err := r.middlewareRegistry.resolve([]routercontract.Middleware{n}); err != nil {
+					errs = append(errs, fmt.Errorf("mux: middleware %q of action %s: %w", n.name, definition.Action, err))
+				}
+			}
+		}
+		handlers[i], err = actionHandler(controller, definition.Action)
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	for i, definition := range definitions {
+		route := r.Route(definition.Methods, definition.Path, handlers[i]).(*Route)
+		route.controller, route.action = reflect.TypeOf(controller), definition.Action
+		if definition.Name != "" {
+			route.Name(definition.Name)
+		}
+		route.Use(definition.Middlewares...)
+	}
+	return nil
+}
+
+func isHTTPMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// ControllerMiddleware is a middleware declared by a controller for its actions.
+type ControllerMiddleware struct {
+	Middleware routercontract.Middleware
+	// Only restricts the middleware to the actions, given as method names.
+	Only []string
+	// Except excludes the actions, given as method names, from the middleware.
+	Except []string
+}
+
+// appliesTo reports whether the middleware applies to the action.
+func (cm ControllerMiddleware) appliesTo(action string) bool {
+	return (len(cm.Only) == 0 || slices.Contains(cm.Only, action)) && !slices.Contains(cm.Except, action)
+}
+
+// MiddlewareController is implemented by controllers which declare middlewares for their actions.
+// The middlewares run after the ones of the router, groups and route,
+// and are declared by the instance which handles the request.
+type MiddlewareController interface {
+	Middlewares() []ControllerMiddleware
+}
+
+// BeforeController is implemented by controllers which do some work before every action,
+// on the instance which handles the request, after it has been constructed.
+type BeforeController interface {
+	Before(request *http.Request)
+}
+
+// AfterController is implemented by controllers which do some work after every action,
+// on the instance which handles the request, with the response returned by the action.
+type AfterController interface {
+	After(request *http.Request, response responseconstract.Responser)
+}
+
+// checkControllerMiddlewares resolves the named middlewares declared by the controller of the route for its action,
+// or for every action if the action of the route isn't known, see [MiddlewareController].
+func (r *Route) checkControllerMiddlewares() error {
+	controller := r.controller
+	if controller == nil {
+		controller = r.router.controller()
+	}
+	if controller == nil {
+		return nil
+	}
+	var instance reflect.Value
+	if controller.Kind() == reflect.Pointer {
+		instance = reflect.New(controller.Elem())
+	} else {
+		instance = reflect.New(controller).Elem()
+	}
+	mc, ok := instance.Interface().(MiddlewareController)
+	if !ok {
+		return nil
+	}
+	var errs []error
+	for _, cm := range mc.Middlewares() {
+		n, ok := cm.Middleware.(*namedMiddleware)
+		if !ok || r.action != "" && !cm.appliesTo(r.action) {
+			continue
+		}
+		if _, err := r.router.middlewareRegistry.resolve([]routercontract.Middleware{n}); err != nil {
+			target := controller.String()
+			if r.action != "" {
+				target += "." + r.action
+			}
+			errs = append(errs, fmt.Errorf("middleware %q of %s: %w", n.name, target, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// invokeAction calls the action of the controller through the middlewares declared by the controller for the action,
+// and the Before and After hooks of the controller.
+func invokeAction(controller any, action string, request *http.Request, call routercontract.Handler) responseconstract.Responser {
+	handler := func(request *http.Request) responseconstract.Responser {
+		if bc, ok := controller.(BeforeController); ok {
+			bc.Before(request)
+		}
+		resp := call(request)
+		if ac, ok := controller.(AfterController); ok {
+			ac.After(request, resp)
+		}
+		return resp
+	}
+	mc, ok := controller.(MiddlewareController)
+	if !ok {
+		return handler(request)
+	}
+	var middlewares []routercontract.Middleware
+	for _, cm := range mc.Middlewares() {
+		if cm.appliesTo(action) {
+			middlewares = append(middlewares, cm.Middleware)
+		}
+	}
+	if len(middlewares) == 0 {
+		return handler(request)
+	}
+	d := dispatchFromContext(request.Context())
+	if d == nil {
+		// the action isn't called by a route, so named middlewares can't be resolved
+		pipes := newConstructorIndexCache().pipes(request, middlewares)
+		return pipeline.New[*http.Request, responseconstract.Responser]().Send(request).Through(pipes...).Then(handler)
+	}
+	middlewares, err := d.route.router.middlewareRegistry.resolve(middlewares)
+	if err != nil {
+		// reported by [Router.Validate]
+		return errorResponse(request, err)
+	}
+	pipes := d.route.router.middlewareConstructorIndexCache.pipes(request, middlewares)
+	d.pipes = append(d.pipes, pipes...)
+	return pipeline.New[*http.Request, responseconstract.Responser]().Send(request).Through(pipes...).Then(handler)
+}
+
+// actionName returns the name of the method of the controller type given as a method expression,
+// e.g. Show for (*UserController).Show, by matching it against the methods of the type.
+// It reports false if the function is not a method expression of the type, e.g. a closure or a method value.
+func actionName(controllerType reflect.Type, method any) (string, bool) {
+	pointer := reflect.ValueOf(method).Pointer()
+	for i := 0; i < controllerType.NumMethod(); i++ {
+		if m := controllerType.Method(i); m.Func.Pointer() == pointer {
+			return m.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,334 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type actionController struct {
+	method string
+}
+
+func (a *actionController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/actions"}
+}
+
+func (a *actionController) Construct(r *http.Request) {
+	a.method = r.Method
+}
+
+func (a *actionController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, a.method+" show")
+}
+
+// Form ends with characters of the suffix of method values, which broke the deprecated binding.
+func (a *actionController) Form(r *http.Request) responseconstract.Responser {
+	return response.New(200, a.method+" form")
+}
+
+type staticActionController struct{}
+
+func (s *staticActionController) Index(r *http.Request) responseconstract.Responser {
+	return response.New(200, "index")
+}
+
+func TestAction(t *testing.T) {
+	r := New()
+	r.Controller(new(actionController), func(router routercontract.Router) {
+		router.GET("/show", Action((*actionController).Show))
+		router.POST("/form", Action((*actionController).Form))
+	})
+	r.GET("/index", Action((*staticActionController).Index))
+
+	for _, c := range []struct {
+		method, path, body string
+	}{
+		{"GET", "/actions/show", "GET show"},
+		{"POST", "/actions/form", "POST form"},
+		{"GET", "/index", "index"},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(c.method, c.path, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code, c.path)
+		assert.Equal(t, c.body, w.Body.String(), c.path)
+	}
+
+	assert.Panics(t, func() {
+		Action(func(c staticActionController, r *http.Request) responseconstract.Responser {
+			return nil
+		})
+	})
+	assert.Panics(t, func() {
+		Action(func(c *actionController, r *http.Request) responseconstract.Responser {
+			return c.Show(r)
+		})
+	}, "closures aren't method expressions")
+}
+
+func TestActionName(t *testing.T) {
+	controllerType := reflect.TypeFor[*actionController]()
+	name, ok := actionName(controllerType, (*actionController).Form)
+	assert.True(t, ok)
+	assert.Equal(t, "Form", name)
+	_, ok = actionName(controllerType, new(actionController).Form)
+	assert.False(t, ok, "method values aren't method expressions")
+}
+
+type fallibleController struct {
+	id string
+}
+
+func (f *fallibleController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/fallible"}
+}
+
+func (f *fallibleController) Construct(r *http.Request) error {
+	f.id = r.URL.Query().Get("id")
+	if f.id == "" {
+		return errors.New("missing id")
+	}
+	return nil
+}
+
+func (f *fallibleController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, f.id)
+}
+
+type shortCircuitController struct{}
+
+func (s *shortCircuitController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/guarded"}
+}
+
+func (s *shortCircuitController) Construct(r *http.Request) responseconstract.Responser {
+	if r.Header.Get("Authorization") == "" {
+		return response.New(http.StatusUnauthorized)
+	}
+	return nil
+}
+
+func (s *shortCircuitController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, "guarded")
+}
+
+func TestAction_abortingConstruct(t *testing.T) {
+	r := New()
+	r.OnError(func(request *http.Request, err error) responseconstract.Responser {
+		return response.New(http.StatusBadRequest, err.Error())
+	})
+	r.Controller(new(fallibleController), func(router routercontract.Router) {
+		router.GET("/show", Action((*fallibleController).Show))
+	})
+	r.Controller(new(shortCircuitController), func(router routercontract.Router) {
+		router.GET("/show", Action((*shortCircuitController).Show))
+	})
+
+	for _, c := range []struct {
+		path, authorization string
+		code                int
+		body                string
+	}{
+		{"/fallible/show?id=1", "", 200, "1"},
+		{"/fallible/show", "", http.StatusBadRequest, "missing id"},
+		{"/guarded/show", "Bearer token", 200, "guarded"},
+		{"/guarded/show", "", http.StatusUnauthorized, ""},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", c.path, nil)
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		r.ServeHTTP(w, req)
+		assert.Equal(t, c.code, w.Code, c.path)
+		assert.Equal(t, c.body, w.Body.String(), c.path)
+	}
+}
+
+func TestRouter_Controller_deprecatedBinding(t *testing.T) {
+	controller := new(actionController)
+	r := New()
+	r.Controller(controller, func(router routercontract.Router) {
+		router.POST("/form", controller.Form)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/actions/form", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "POST form", w.Body.String())
+}
+
+type declarativeController struct {
+	ShowRoute Endpoint `route:"GET /{id}" name:"posts.show" middleware:"auth"`
+	Publish   Endpoint `route:"POST,PUT /{id}/publish" action:"Store"`
+	Archive   Endpoint `route:"patch, delete /{id}/archive" action:"Store"`
+	method    string
+}
+
+func (d *declarativeController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/posts"}
+}
+
+func (d *declarativeController) Construct(r *http.Request) {
+	d.method = r.Method
+}
+
+func (d *declarativeController) Routes() []RouteDefinition {
+	return []RouteDefinition{
+		{Methods: []string{"GET"}, Path: "", Action: "Index", Name: "posts.index", Middlewares: []routercontract.Middleware{new(nonStaticMiddleware)}},
+	}
+}
+
+func (d *declarativeController) Index(r *http.Request) responseconstract.Responser {
+	return response.New(200, d.method+" index")
+}
+
+func (d *declarativeController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, d.method+" show "+r.Context().Value(idKey).(string))
+}
+
+func (d *declarativeController) Store(r *http.Request) responseconstract.Responser {
+	return response.New(201, d.method+" store")
+}
+
+type invalidDeclarativeController struct {
+	MissingRoute Endpoint `route:"GET /missing"`
+	Typo         Endpoint `route:"GET /typo" action:"Signature" middleware:"auht"`
+	BadRoute     Endpoint `route:"/bad"`
+	Wrong        Endpoint `route:"FETCH /wrong" action:"Signature"`
+}
+
+func (i *invalidDeclarativeController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/invalid"}
+}
+
+func (i *invalidDeclarativeController) Signature(r *http.Request) {}
+
+func TestRouter_Controller_declaredRoutes(t *testing.T) {
+	r := New()
+	r.AliasMiddleware("auth", new(staticMiddleware))
+	r.Controller(new(declarativeController), nil)
+
+	for _, c := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/posts?id=1", 200, "GET index"},
+		{"GET", "/posts", 403, ""},
+		{"GET", "/posts/1?id=1", 200, "GET show 1"},
+		{"GET", "/posts/1", 403, ""},
+		{"POST", "/posts/1/publish", 201, "POST store"},
+		{"PUT", "/posts/1/publish", 201, "PUT store"},
+		{"PATCH", "/posts/1/archive", 201, "PATCH store"},
+		{"DELETE", "/posts/1/archive", 201, "DELETE store"},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(c.method, c.path, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, c.code, w.Code, c.method+" "+c.path)
+		assert.Equal(t, c.body, w.Body.String(), c.method+" "+c.path)
+	}
+	assert.NotNil(t, r.Get("posts.index"))
+	assert.NotNil(t, r.Get("posts.show"))
+
+	defer func() {
+		err, _ := recover().(error)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "has no method Missing")
+			assert.Contains(t, err.Error(), "BadRoute")
+			assert.Contains(t, err.Error(), "FETCH")
+			assert.Contains(t, err.Error(), "invalidDeclarativeController.Signature")
+			assert.Contains(t, err.Error(), "auht")
+		}
+	}()
+	New().Controller(new(invalidDeclarativeController), nil)
+}
+
+type hookedController struct {
+	steps []string
+}
+
+func (h *hookedController) RouteGroup() routercontract.RouteGroup {
+	return &RouteGroup{Prefix: "/hooked"}
+}
+
+func (h *hookedController) Construct(r *http.Request) {
+	h.steps = append(h.steps, "construct")
+}
+
+func (h *hookedController) Middlewares() []ControllerMiddleware {
+	h.steps = append(h.steps, "middlewares")
+	return []ControllerMiddleware{
+		{Middleware: Named("session")},
+		{Middleware: new(authMiddleware), Except: []string{"Index"}},
+		{Middleware: new(terminableMiddleware), Only: []string{"Show"}},
+	}
+}
+
+func (h *hookedController) Before(r *http.Request) {
+	h.steps = append(h.steps, "before")
+}
+
+func (h *hookedController) After(r *http.Request, resp responseconstract.Responser) {
+	resp.SetHeader("Steps", strings.Join(h.steps, ","))
+}
+
+func (h *hookedController) Index(r *http.Request) responseconstract.Responser {
+	h.steps = append(h.steps, "index")
+	return response.New(200, strings.Join(r.Header.Values("Order"), ","))
+}
+
+func (h *hookedController) Show(r *http.Request) responseconstract.Responser {
+	h.steps = append(h.steps, "show")
+	return response.New(200, strings.Join(r.Header.Values("Order"), ","))
+}
+
+func TestMiddlewareController(t *testing.T) {
+	r := New()
+	r.AliasMiddleware("session", new(sessionMiddleware))
+	r.Use(new(logMiddleware))
+	r.Controller(new(hookedController), func(router routercontract.Router) {
+		router.GET("/", Action((*hookedController).Index))
+		router.GET("/show", Action((*hookedController).Show))
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hooked/", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "log,session", w.Body.String())
+	assert.Equal(t, "construct,middlewares,before,index", w.Header().Get("Steps"))
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/hooked/show", nil)
+	r.ServeHTTP(w2, req2)
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, "log,session,auth", w2.Body.String())
+	assert.Equal(t, "construct,middlewares,before,show", w2.Header().Get("Steps"))
+	assert.Equal(t, "GET /hooked/show", <-terminatedRequests)
+
+	t.Run("unregistered", func(t *testing.T) {
+		r := New()
+		r.Controller(new(hookedController), func(router routercontract.Router) {
+			RouteAction(router, []string{http.MethodGet}, "/show", (*hookedController).Show)
+		})
+		err := r.Validate()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "/hooked/show")
+			assert.Contains(t, err.Error(), `"session"`)
+			assert.Contains(t, err.Error(), "hookedController.Show")
+		}
+		assert.Equal(t, 500, serve(r, "GET", "/hooked/show").Code)
+	})
+}
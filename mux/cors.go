@@ -0,0 +1,276 @@
+package mux
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/exception"
+	"github.com/gopi-frame/response"
+)
+
+// CORSPolicy is the cross-origin resource sharing policy of routes.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to make requests:
+	// exact origins, e.g. https://example.com, wildcard subdomains, e.g. https://*.example.com, or * for any origin.
+	AllowedOrigins []string
+	// AllowedOriginPatterns are the patterns of further origins allowed to make requests.
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowOriginFunc allows further origins to make requests.
+	AllowOriginFunc func(request *http.Request, origin string) bool
+	// AllowedMethods are the methods allowed in cross-origin requests, which default to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in cross-origin requests besides the CORS-safelisted ones,
+	// or * for any header.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers exposed to cross-origin requests besides the CORS-safelisted ones.
+	ExposedHeaders []string
+	// AllowCredentials allows cross-origin requests with credentials, e.g. cookies.
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// CORS creates a middleware which applies the CORS policy.
+// A policy which allows credentials from any origin, which would let any site make credentialed requests,
+// is reported by [Router.Validate], and the routes using it answer with the response of the error handler.
+//
+// The middleware can be used on the router, groups and routes: the policy of the innermost one applies to a route,
+// so the policy of a group or route overrides the policy of the router.
+// Preflight requests are answered for every route with a policy, before routing by method,
+// so that they don't need OPTIONS routes and don't run through the other middlewares of the route.
+func CORS(policy CORSPolicy) *CORSMiddleware {
+	m := new(CORSMiddleware)
+	if policy.AllowCredentials && slices.Contains(policy.AllowedOrigins, "*") {
+		m.err = exception.NewArgumentException("policy", policy.AllowedOrigins, "CORS policy can't allow credentials from any origin")
+	}
+	if len(policy.AllowedMethods) == 0 {
+		policy.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	// the methods of the caller are left as is
+	policy.AllowedMethods = slices.Clone(policy.AllowedMethods)
+	for i, method := range policy.AllowedMethods {
+		policy.AllowedMethods[i] = strings.ToUpper(method)
+	}
+	m.policy = policy
+	return m
+}
+
+// CORSMiddleware applies a CORS policy, see [CORS].
+type CORSMiddleware struct {
+	policy CORSPolicy
+	// err reports an invalid policy.
+	err error
+}
+
+func (m *CORSMiddleware) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	// only the innermost policy of the route applies
+	if d := dispatchFromContext(request.Context()); d != nil && d.route.corsMiddleware() != m {
+		return next(request)
+	}
+	if m.err != nil {
+		return errorResponse(request, m.err)
+	}
+	if isPreflight(request) {
+		return m.preflight(request)
+	}
+	resp := next(request)
+	if resp == nil {
+		resp = response.New(http.StatusOK)
+	}
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return resp
+	}
+	if m.allowsOrigin(request, origin) {
+		m.setOrigin(resp, origin)
+		if len(m.policy.ExposedHeaders) != 0 {
+			resp.SetHeader("Access-Control-Expose-Headers", strings.Join(m.policy.ExposedHeaders, ", "))
+		}
+	}
+	// the response depends on the origin, whether it is allowed or not
+	return response.NewHandlerWrapper(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		resp.ServeHTTP(&varyWriter{ResponseWriter: w, values: []string{"Origin"}}, request)
+	}))
+}
+
+// preflight answers the preflight request.
+// If the request is not allowed, the response has no CORS headers, so that the browser fails the request.
+func (m *CORSMiddleware) preflight(request *http.Request) responseconstract.Responser {
+	if m.err != nil {
+		return errorResponse(request, m.err)
+	}
+	resp := response.New(http.StatusNoContent)
+	resp.SetHeader("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	origin := request.Header.Get("Origin")
+	method := strings.ToUpper(request.Header.Get("Access-Control-Request-Method"))
+	if !m.allowsOrigin(request, origin) || !slices.Contains(m.policy.AllowedMethods, method) {
+		return resp
+	}
+	var headers []string
+	for _, header := range strings.Split(request.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+		if !m.allowsHeader(header) {
+			return resp
+		}
+		headers = append(headers, header)
+	}
+	m.setOrigin(resp, origin)
+	resp.SetHeader("Access-Control-Allow-Methods", strings.Join(m.policy.AllowedMethods, ", "))
+	if len(headers) != 0 {
+		resp.SetHeader("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if m.policy.MaxAge > 0 {
+		resp.SetHeader("Access-Control-Max-Age", strconv.Itoa(int(m.policy.MaxAge.Seconds())))
+	}
+	return resp
+}
+
+func (m *CORSMiddleware) setOrigin(resp responseconstract.Responser, origin string) {
+	if m.policy.AllowCredentials {
+		resp.SetHeader("Access-Control-Allow-Origin", origin)
+		resp.SetHeader("Access-Control-Allow-Credentials", "true")
+	} else if slices.Contains(m.policy.AllowedOrigins, "*") {
+		resp.SetHeader("Access-Control-Allow-Origin", "*")
+	} else {
+		resp.SetHeader("Access-Control-Allow-Origin", origin)
+	}
+}
+
+func (m *CORSMiddleware) allowsOrigin(request *http.Request, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range m.policy.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(allowed, "*"); ok {
+			// a wildcard matches one or more subdomains
+			subdomain, ok := strings.CutPrefix(origin, prefix)
+			if ok && strings.HasSuffix(subdomain, suffix) && len(subdomain) > len(suffix) && !strings.ContainsAny(subdomain, "/:") {
+				return true
+			}
+		}
+	}
+	for _, pattern := range m.policy.AllowedOriginPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return m.policy.AllowOriginFunc != nil && m.policy.AllowOriginFunc(request, origin)
+}
+
+// corsSafelistedHeaders are the request headers always allowed in cross-origin requests.
+var corsSafelistedHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type", "Range"}
+
+func (m *CORSMiddleware) allowsHeader(header string) bool {
+	header = http.CanonicalHeaderKey(header)
+	if slices.Contains(corsSafelistedHeaders, header) {
+		return true
+	}
+	for _, allowed := range m.policy.AllowedHeaders {
+		if allowed == "*" || http.CanonicalHeaderKey(allowed) == header {
+			return true
+		}
+	}
+	return false
+}
+
+// varyWriter adds values to the Vary header of the response when it is written,
+// keeping the values set by the response, e.g. Accept-Encoding.
+type varyWriter struct {
+	http.ResponseWriter
+
+	values []string
+	added  bool
+}
+
+func (w *varyWriter) addVary() {
+	if w.added {
+		return
+	}
+	w.added = true
+	addVary(w.Header(), w.values...)
+}
+
+func (w *varyWriter) WriteHeader(status int) {
+	w.addVary()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *varyWriter) Write(b []byte) (int, error) {
+	w.addVary()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *varyWriter) Flush() {
+	w.addVary()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (w *varyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// addVary appends the values to the Vary header, unless they are already listed.
+func addVary(header http.Header, values ...string) {
+	var existing []string
+	for _, line := range header.Values("Vary") {
+		for _, value := range strings.Split(line, ",") {
+			existing = append(existing, strings.ToLower(strings.TrimSpace(value)))
+		}
+	}
+	for _, value := range values {
+		if !slices.Contains(existing, strings.ToLower(value)) && !slices.Contains(existing, "*") {
+			header.Add("Vary", value)
+		}
+	}
+}
+
+// isPreflight reports whether the request is a CORS preflight request.
+func isPreflight(request *http.Request) bool {
+	return request.Method == http.MethodOptions && request.Header.Get("Origin") != "" && request.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// corsMiddleware returns the innermost CORS middleware of the route, or nil if there is none.
+func (r *Route) corsMiddleware() *CORSMiddleware {
+	return r.resolveChain().cors
+}
+
+// innermostCORSMiddleware returns the last CORS middleware of the chain, or nil if there is none.
+func innermostCORSMiddleware(middlewares []routercontract.Middleware) *CORSMiddleware {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware := middlewares[i]
+		if am, ok := middleware.(*aliasedMiddleware); ok {
+			middleware = am.Middleware
+		}
+		if cm, ok := middleware.(*CORSMiddleware); ok {
+			return cm
+		}
+	}
+	return nil
+}
+
+// corsError returns the error of the first invalid CORS policy of the middlewares, if any.
+func corsError(middlewares []routercontract.Middleware) error {
+	for _, middleware := range middlewares {
+		if am, ok := middleware.(*aliasedMiddleware); ok {
+			middleware = am.Middleware
+		}
+		if cm, ok := middleware.(*CORSMiddleware); ok && cm.err != nil {
+			return cm.err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func corsRequest(r http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	hello := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	}
+	r := New()
+	r.Use(CORS(CORSPolicy{
+		AllowedOrigins:        []string{"https://example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowOriginFunc: func(request *http.Request, origin string) bool {
+			return origin == "https://partner.test"
+		},
+		AllowedMethods: []string{"get", "post"},
+		// PUT routes are not allowed by the policy
+		AllowedHeaders: []string{"X-Token"},
+		ExposedHeaders: []string{"X-Total"},
+		MaxAge:         time.Hour,
+	}))
+	r.GET("/items", hello)
+	r.POST("/items", hello)
+	r.PUT("/items", hello)
+	r.Group(&RouteGroup{Prefix: "/public"}, func(router routercontract.Router) {
+		router.Use(CORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET", "PUT"}}))
+		router.GET("/items", hello)
+		router.PUT("/items", hello)
+	})
+	r.GET("/private", hello).(*Route).Use(CORS(CORSPolicy{AllowedOrigins: []string{"https://admin.test"}, AllowCredentials: true}))
+	r.GET("/none", hello).(*Route).WithoutMiddleware(&CORSMiddleware{})
+
+	t.Run("preflight", func(t *testing.T) {
+		w := corsRequest(r, "OPTIONS", "/items", map[string]string{
+			"Origin":                         "https://example.com",
+			"Access-Control-Request-Method":  "POST",
+			"Access-Control-Request-Headers": "x-token, content-type",
+		})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "x-token, content-type", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("preflight not allowed", func(t *testing.T) {
+		for _, headers := range []map[string]string{
+			{"Origin": "https://evil.test", "Access-Control-Request-Method": "GET"},
+			{"Origin": "https://example.com", "Access-Control-Request-Method": "PUT"},
+			{"Origin": "https://example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Other"},
+		} {
+			w := corsRequest(r, "OPTIONS", "/items", headers)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+		// no route for the requested method
+		w := corsRequest(r, "OPTIONS", "/private", map[string]string{"Origin": "https://admin.test", "Access-Control-Request-Method": "DELETE"})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		for _, origin := range []string{"https://example.com", "https://api.example.org", "https://a.b.example.org", "http://localhost:3000", "https://partner.test"} {
+			w := corsRequest(r, "GET", "/items", map[string]string{"Origin": origin})
+			assert.Equal(t, 200, w.Code, origin)
+			assert.Equal(t, origin, w.Header().Get("Access-Control-Allow-Origin"), origin)
+			assert.Equal(t, "X-Total", w.Header().Get("Access-Control-Expose-Headers"), origin)
+			assert.Equal(t, "Origin", w.Header().Get("Vary"), origin)
+		}
+		for _, origin := range []string{"https://evil.test", "https://example.org", "https://evil.test/.example.org", "http://localhost:x"} {
+			w := corsRequest(r, "GET", "/items", map[string]string{"Origin": origin})
+			assert.Equal(t, 200, w.Code, origin)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+		}
+		w := corsRequest(r, "GET", "/items", nil)
+		assert.Equal(t, "hello", w.Body.String())
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("overridden policies", func(t *testing.T) {
+		w := corsRequest(r, "OPTIONS", "/public/items", map[string]string{"Origin": "https://any.test", "Access-Control-Request-Method": "PUT"})
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, PUT", w.Header().Get("Access-Control-Allow-Methods"))
+
+		w = corsRequest(r, "GET", "/public/items", map[string]string{"Origin": "https://any.test"})
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Expose-Headers"))
+
+		w = corsRequest(r, "GET", "/private", map[string]string{"Origin": "https://example.com"})
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		w = corsRequest(r, "GET", "/private", map[string]string{"Origin": "https://admin.test"})
+		assert.Equal(t, "https://admin.test", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+		w = corsRequest(r, "GET", "/none", map[string]string{"Origin": "https://example.com"})
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		w = corsRequest(r, "OPTIONS", "/none", map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "GET"})
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCORS_vary(t *testing.T) {
+	r := New()
+	r.Use(CORS(CORSPolicy{AllowedOrigins: []string{"https://example.com"}}))
+	r.GET("/compressed", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello").SetHeader("Vary", "Accept-Encoding")
+	})
+	r.GET("/origin", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello").SetHeader("Vary", "origin")
+	})
+
+	for _, origin := range []string{"https://example.com", "https://evil.test"} {
+		w := corsRequest(r, "GET", "/compressed", map[string]string{"Origin": origin})
+		assert.Equal(t, []string{"Accept-Encoding", "Origin"}, w.Header().Values("Vary"), origin)
+	}
+	w := corsRequest(r, "GET", "/origin", map[string]string{"Origin": "https://example.com"})
+	assert.Equal(t, []string{"origin"}, w.Header().Values("Vary"))
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_credentialsFromAnyOrigin(t *testing.T) {
+	r := New()
+	r.GET("/any", func(request *http.Request) responseconstract.Responser {
+		return response.New(200)
+	}).Use(CORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}))
+	r.GET("/example", func(request *http.Request) responseconstract.Responser {
+		return response.New(200)
+	}).Use(CORS(CORSPolicy{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true}))
+
+	err := r.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/any")
+		assert.Contains(t, err.Error(), "can't allow credentials from any origin")
+		assert.NotContains(t, err.Error(), "/example")
+	}
+	assert.Equal(t, 500, serve(r, "GET", "/any").Code)
+	assert.Equal(t, 200, serve(r, "GET", "/example").Code)
+}
+
+func TestCORS_allowedMethods(t *testing.T) {
+	methods := []string{"get", "put"}
+	m := CORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: methods})
+	assert.Equal(t, []string{"GET", "PUT"}, m.policy.AllowedMethods)
+	assert.Equal(t, []string{"get", "put"}, methods, "the policy of the caller is left as is")
+}
@@ -0,0 +1,106 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// WithoutAutoHead stops the GET routes of the router and all its groups from answering HEAD requests,
+// which they do by default, see [Route.WithoutAutoHead].
+// Routes registered afterwards aren't registered for HEAD at all.
+func (r *Router) WithoutAutoHead() *Router {
+	r.noAutoHead = true
+	return r
+}
+
+// answersHead reports whether the GET routes of the router answer HEAD requests.
+func (r *Router) answersHead() bool {
+	for ; r != nil; r = r.parent {
+		if r.noAutoHead {
+			return false
+		}
+	}
+	return true
+}
+
+// WithoutAutoHead stops the GET route from answering HEAD requests.
+//
+// By default, GET routes answer HEAD requests unless a HEAD route matches them:
+// the request runs through the same middlewares and handler,
+// and the body of the response is discarded, while its headers are kept and its Content-Length is set.
+func (r *Route) WithoutAutoHead() *Route {
+	r.noAutoHead = true
+	return r
+}
+
+// answersHead reports whether the route answers HEAD requests for its GET method.
+func (r *Route) answersHead() bool {
+	return r.autoHead && !r.noAutoHead && r.router.answersHead()
+}
+
+// matchHead lets HEAD requests through to the GET route unless a HEAD route matches them,
+// or the route doesn't answer them anymore, so that later routes can.
+func (r *Route) matchHead(req *http.Request, _ *mux.RouteMatch) bool {
+	if req.Method != http.MethodHead {
+		return true
+	}
+	if !r.answersHead() {
+		return false
+	}
+	for _, head := range r.router.root().headRoutes {
+		var match mux.RouteMatch
+		if head.Match(req, &match) {
+			return false
+		}
+	}
+	return true
+}
+
+// headWriter discards the body of the response to a HEAD request answered by a GET route.
+// The header is written by finish, with the Content-Length of the discarded body unless it is set.
+type headWriter struct {
+	http.ResponseWriter
+
+	status   int
+	bytes    int64
+	finished bool
+}
+
+func (w *headWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.bytes += int64(len(b))
+	return len(b), nil
+}
+
+// Flush does nothing, since the header can only be written once the size of the body is known.
+func (w *headWriter) Flush() {}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (w *headWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headWriter) finish() {
+	if w.finished {
+		return
+	}
+	w.finished = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	header := w.Header()
+	if w.bytes > 0 && header.Get("Content-Length") == "" && header.Get("Transfer-Encoding") == "" {
+		header.Set("Content-Length", strconv.FormatInt(w.bytes, 10))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
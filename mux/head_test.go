@@ -0,0 +1,102 @@
+package mux
+
+import (
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoHead(t *testing.T) {
+	hello := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello").SetHeader("X-Method", request.Method)
+	}
+	r := New()
+	r.Use(new(staticMiddleware))
+	r.GET("/hello", hello)
+	r.GET("/explicit", hello)
+	r.HEAD("/explicit", func(request *http.Request) responseconstract.Responser {
+		return response.New(http.StatusNoContent).SetHeader("X-Explicit", "true")
+	})
+	r.GET("/disabled", hello).(*Route).WithoutAutoHead()
+	r.Group(&RouteGroup{Prefix: "/group"}, func(router routercontract.Router) {
+		router.(*Router).WithoutAutoHead()
+		router.GET("/hello", hello)
+	})
+	r.Handle([]string{"GET"}, "/handler", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("partial"))
+	}))
+
+	t.Run("GET route", func(t *testing.T) {
+		get := serve(r, "GET", "/hello?id=1")
+		head := serve(r, "HEAD", "/hello?id=1")
+		assert.Equal(t, 200, head.Code)
+		assert.Empty(t, head.Body.String())
+		assert.Equal(t, "HEAD", head.Header().Get("X-Method"))
+		assert.Equal(t, get.Header().Get("Custom-Header"), head.Header().Get("Custom-Header"))
+		assert.Equal(t, "5", head.Header().Get("Content-Length"))
+
+		// middlewares run as for GET
+		head = serve(r, "HEAD", "/hello")
+		assert.Equal(t, http.StatusForbidden, head.Code)
+		assert.Empty(t, head.Body.String())
+	})
+
+	t.Run("http.Handler", func(t *testing.T) {
+		head := serve(r, "HEAD", "/handler?id=1")
+		assert.Equal(t, http.StatusPartialContent, head.Code)
+		assert.Equal(t, "100", head.Header().Get("Content-Length"))
+		assert.Empty(t, head.Body.String())
+	})
+
+	t.Run("explicit HEAD route", func(t *testing.T) {
+		head := serve(r, "HEAD", "/explicit?id=1")
+		assert.Equal(t, http.StatusNoContent, head.Code)
+		assert.Equal(t, "true", head.Header().Get("X-Explicit"))
+		assert.Equal(t, "hello", serve(r, "GET", "/explicit?id=1").Body.String())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		for _, path := range []string{"/disabled?id=1", "/group/hello?id=1"} {
+			head := serve(r, "HEAD", path)
+			assert.Equal(t, http.StatusMethodNotAllowed, head.Code, path)
+			assert.Equal(t, "GET, OPTIONS", head.Header().Get("Allow"), path)
+			assert.Equal(t, 200, serve(r, "GET", path).Code, path)
+		}
+	})
+}
+
+func TestAutoHead_registration(t *testing.T) {
+	hello := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	}
+	r := New()
+	lower := r.Route([]string{"get"}, "/lower", hello).(*Route)
+	var disabled *Route
+	r.Group(&RouteGroup{Prefix: "/group"}, func(router routercontract.Router) {
+		router.(*Router).WithoutAutoHead()
+		disabled = router.GET("/hello", hello).(*Route)
+	})
+	r.GET("/files/a.txt", hello).(*Route).WithoutAutoHead()
+	r.Static("/files", http.FS(fstest.MapFS{"a.txt": {Data: []byte("static")}}))
+
+	methods, _ := lower.GetMethods()
+	assert.Equal(t, []string{"GET", "HEAD"}, methods)
+	assert.Equal(t, 200, serve(r, "HEAD", "/lower").Code)
+
+	methods, _ = disabled.GetMethods()
+	assert.Equal(t, []string{"GET"}, methods)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(r, "HEAD", "/group/hello").Code)
+
+	// the GET route doesn't hide later routes answering HEAD
+	head := serve(r, "HEAD", "/files/a.txt")
+	assert.Equal(t, 200, head.Code)
+	assert.Equal(t, "6", head.Header().Get("Content-Length"))
+	assert.Equal(t, "hello", serve(r, "GET", "/files/a.txt").Body.String())
+}
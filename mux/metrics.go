@@ -0,0 +1,277 @@
+package mux
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+)
+
+// DefaultDurationBuckets are the default buckets of the request duration histogram, in seconds.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the default buckets of the response size histogram, in bytes.
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7, 1e8}
+
+// MetricsOption configures the metrics created by [NewMetrics].
+type MetricsOption func(metrics *Metrics)
+
+// MetricsNamespace prefixes the names of the metrics with the namespace, e.g. myapp_http_requests_total.
+func MetricsNamespace(namespace string) MetricsOption {
+	return func(metrics *Metrics) {
+		metrics.namespace = namespace
+	}
+}
+
+// MetricsDurationBuckets sets the buckets of the request duration histogram, in seconds.
+func MetricsDurationBuckets(buckets ...float64) MetricsOption {
+	return func(metrics *Metrics) {
+		metrics.durationBuckets = buckets
+	}
+}
+
+// MetricsSizeBuckets sets the buckets of the response size histogram, in bytes.
+func MetricsSizeBuckets(buckets ...float64) MetricsOption {
+	return func(metrics *Metrics) {
+		metrics.sizeBuckets = buckets
+	}
+}
+
+// Metrics is a middleware which records metrics of the requests handled by routes:
+// request counts, request duration and response size histograms, and requests in flight.
+//
+// Metrics are labelled by the route template rather than the request path, the route name, the method
+// and the status class, e.g. 2xx, to keep the number of series bounded.
+// Raw routes, requests which match no route and requests aborted by a middleware before it are not recorded.
+// [Metrics.ServeHTTP] exposes them in the Prometheus text exposition format, see [Router.Metrics].
+type Metrics struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu       sync.Mutex
+	requests map[routeLabels]*routeMetrics
+	inFlight map[routeLabels]int64
+}
+
+// routeLabels are the labels of the metrics of a route.
+// The status class is empty for requests in flight.
+type routeLabels struct {
+	route, name, method, status string
+}
+
+type routeMetrics struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, value float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	for i, bound := range buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+}
+
+func NewMetrics(options ...MetricsOption) *Metrics {
+	metrics := &Metrics{
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+		requests:        make(map[routeLabels]*routeMetrics),
+		inFlight:        make(map[routeLabels]int64),
+	}
+	for _, option := range options {
+		option(metrics)
+	}
+	metrics.durationBuckets = slices.Clone(metrics.durationBuckets)
+	slices.Sort(metrics.durationBuckets)
+	metrics.sizeBuckets = slices.Clone(metrics.sizeBuckets)
+	slices.Sort(metrics.sizeBuckets)
+	return metrics
+}
+
+func (m *Metrics) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	if labels, ok := metricsLabels(request); ok {
+		m.mu.Lock()
+		m.inFlight[labels]++
+		m.mu.Unlock()
+		d := dispatchFromContext(request.Context())
+		d.inFlight = append(d.inFlight, inFlightRequest{metrics: m, labels: labels})
+	}
+	return next(request)
+}
+
+func (m *Metrics) Terminate(request *http.Request, _ responseconstract.Responser) {
+	labels, ok := metricsLabels(request)
+	if !ok {
+		return
+	}
+	d := dispatchFromContext(request.Context())
+	// the request isn't recorded if a middleware before aborted it
+	if !slices.ContainsFunc(d.inFlight, func(f inFlightRequest) bool { return f.metrics == m }) {
+		return
+	}
+	duration := d.finished.Sub(d.started).Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	labels.status = strconv.Itoa(d.writer.Status()/100) + "xx"
+	rm, ok := m.requests[labels]
+	if !ok {
+		rm = new(routeMetrics)
+		m.requests[labels] = rm
+	}
+	rm.count++
+	rm.duration.observe(m.durationBuckets, duration)
+	rm.size.observe(m.sizeBuckets, float64(d.writer.bytes))
+}
+
+// inFlightRequest is a request counted in flight by the metrics, until the response has been written.
+type inFlightRequest struct {
+	metrics *Metrics
+	labels  routeLabels
+}
+
+// land stops counting the request in flight.
+func (f inFlightRequest) land() {
+	f.metrics.mu.Lock()
+	f.metrics.inFlight[f.labels]--
+	f.metrics.mu.Unlock()
+}
+
+// landInFlight stops counting the request in flight by the metrics,
+// once the response has been sent or if the handler panicked.
+func (d *dispatch) landInFlight() {
+	if d.landed {
+		return
+	}
+	d.landed = true
+	for _, f := range d.inFlight {
+		f.land()
+	}
+}
+
+// metricsLabels returns the labels of the route handling the request, without status class.
+func metricsLabels(request *http.Request) (routeLabels, bool) {
+	d := dispatchFromContext(request.Context())
+	if d == nil {
+		return routeLabels{}, false
+	}
+	template, err := d.route.GetPathTemplate()
+	if err != nil {
+		template = ""
+	}
+	return routeLabels{route: template, name: d.route.GetName(), method: metricsMethod(request.Method)}, true
+}
+
+// metricsMethod returns the method label of the request method,
+// which is OTHER for non-standard methods, so that clients can't create unbounded series,
+// e.g. on routes matching any method.
+func metricsMethod(method string) string {
+	if isHTTPMethod(method) {
+		return method
+	}
+	return "OTHER"
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf := bufio.NewWriter(w)
+	m.write(buf)
+	_ = buf.Flush()
+}
+
+func (m *Metrics) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	requests := make([]routeLabels, 0, len(m.requests))
+	for labels := range m.requests {
+		requests = append(requests, labels)
+	}
+	slices.SortFunc(requests, compareRouteLabels)
+	inFlight := make([]routeLabels, 0, len(m.inFlight))
+	for labels := range m.inFlight {
+		inFlight = append(inFlight, labels)
+	}
+	slices.SortFunc(inFlight, compareRouteLabels)
+
+	name := m.name("http_requests_total")
+	fmt.Fprintf(w, "# HELP %s Total number of HTTP requests handled by routes.\n# TYPE %s counter\n", name, name)
+	for _, labels := range requests {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels, m.requests[labels].count)
+	}
+	name = m.name("http_request_duration_seconds")
+	fmt.Fprintf(w, "# HELP %s Duration of HTTP requests handled by routes.\n# TYPE %s histogram\n", name, name)
+	for _, labels := range requests {
+		writeHistogram(w, name, labels, m.durationBuckets, &m.requests[labels].duration, m.requests[labels].count)
+	}
+	name = m.name("http_response_size_bytes")
+	fmt.Fprintf(w, "# HELP %s Size of HTTP responses written by routes.\n# TYPE %s histogram\n", name, name)
+	for _, labels := range requests {
+		writeHistogram(w, name, labels, m.sizeBuckets, &m.requests[labels].size, m.requests[labels].count)
+	}
+	name = m.name("http_requests_in_flight")
+	fmt.Fprintf(w, "# HELP %s Number of HTTP requests being handled by routes.\n# TYPE %s gauge\n", name, name)
+	for _, labels := range inFlight {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labels, m.inFlight[labels])
+	}
+}
+
+func writeHistogram(w *bufio.Writer, name string, labels routeLabels, buckets []float64, h *histogram, count uint64) {
+	for i, bound := range buckets {
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, count)
+}
+
+func (m *Metrics) name(name string) string {
+	if m.namespace == "" {
+		return name
+	}
+	return m.namespace + "_" + name
+}
+
+// String formats the labels as in the Prometheus text exposition format, e.g. route="/users/{id}",name="",method="GET".
+func (l routeLabels) String() string {
+	s := "route=" + quoteLabel(l.route) + ",name=" + quoteLabel(l.name) + ",method=" + quoteLabel(l.method)
+	if l.status != "" {
+		s += ",status=" + quoteLabel(l.status)
+	}
+	return s
+}
+
+func compareRouteLabels(a, b routeLabels) int {
+	return strings.Compare(a.String(), b.String())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
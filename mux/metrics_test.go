@@ -0,0 +1,114 @@
+package mux
+
+import (
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+// denyMiddleware ends every request without calling the next handler.
+type denyMiddleware struct{}
+
+func (d *denyMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	return response.New(http.StatusForbidden)
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics(MetricsNamespace("app"), MetricsDurationBuckets(10, 0.5), MetricsSizeBuckets(4, 100))
+	r := New()
+	r.Use(metrics)
+	r.GET("/users/{id}", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	}).Name("users.show")
+	r.Group(&RouteGroup{Prefix: "/api"}, func(router routercontract.Router) {
+		router.Use(new(apiKeyMiddleware))
+		router.GET("/items", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, "items")
+		})
+	})
+	r.Handle([]string{"POST"}, "/quote\"s", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	r.Metrics("/metrics", metrics)
+
+	serve(r, "GET", "/users/1")
+	serve(r, "GET", "/users/2")
+	serve(r, "GET", "/missing")
+	serve(r, "GET", "/api/items")
+	serve(r, "POST", "/quote\"s")
+
+	w := serve(r, "GET", "/metrics")
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE app_http_requests_total counter",
+		`app_http_requests_total{route="/users/{id}",name="users.show",method="GET",status="2xx"} 2`,
+		`app_http_requests_total{route="/api/items",name="",method="GET",status="4xx"} 1`,
+		`app_http_requests_total{route="/quote\"s",name="",method="POST",status="4xx"} 1`,
+		"# TYPE app_http_request_duration_seconds histogram",
+		`app_http_request_duration_seconds_bucket{route="/users/{id}",name="users.show",method="GET",status="2xx",le="0.5"} 2`,
+		`app_http_request_duration_seconds_bucket{route="/users/{id}",name="users.show",method="GET",status="2xx",le="10"} 2`,
+		`app_http_request_duration_seconds_bucket{route="/users/{id}",name="users.show",method="GET",status="2xx",le="+Inf"} 2`,
+		`app_http_request_duration_seconds_count{route="/users/{id}",name="users.show",method="GET",status="2xx"} 2`,
+		`app_http_response_size_bytes_bucket{route="/users/{id}",name="users.show",method="GET",status="2xx",le="4"} 0`,
+		`app_http_response_size_bytes_bucket{route="/users/{id}",name="users.show",method="GET",status="2xx",le="100"} 2`,
+		`app_http_response_size_bytes_sum{route="/users/{id}",name="users.show",method="GET",status="2xx"} 10`,
+		"# TYPE app_http_requests_in_flight gauge",
+		`app_http_requests_in_flight{route="/users/{id}",name="users.show",method="GET"} 0`,
+		`app_http_requests_in_flight{route="/api/items",name="",method="GET"} 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, "/missing")
+	assert.NotContains(t, body, `route="/metrics"`)
+
+	t.Run("aborted before", func(t *testing.T) {
+		metrics := NewMetrics()
+		r := New()
+		r.GET("/items", func(request *http.Request) responseconstract.Responser {
+			return nil
+		}).Use(new(denyMiddleware), metrics)
+		r.Metrics("/metrics", metrics)
+
+		serve(r, "GET", "/items")
+		body := serve(r, "GET", "/metrics").Body.String()
+		assert.NotContains(t, body, `http_requests_total{`)
+		assert.NotContains(t, body, "http_requests_in_flight{")
+	})
+
+	t.Run("bounded methods", func(t *testing.T) {
+		metrics := NewMetrics()
+		r := New()
+		r.Use(metrics)
+		r.Static("/static", http.FS(fstest.MapFS{"a.txt": {Data: []byte("a")}}))
+		r.Metrics("/metrics", metrics)
+
+		serve(r, "FOO", "/static/a.txt")
+		serve(r, "BAR", "/static/a.txt")
+		body := serve(r, "GET", "/metrics").Body.String()
+		assert.Contains(t, body, `method="OTHER"`)
+		assert.NotContains(t, body, `method="FOO"`)
+		assert.NotContains(t, body, `method="BAR"`)
+	})
+
+	t.Run("panicking handler", func(t *testing.T) {
+		metrics := NewMetrics()
+		r := New()
+		r.Use(metrics)
+		r.GET("/panic", func(request *http.Request) responseconstract.Responser {
+			panic("boom")
+		})
+		r.Metrics("/metrics", metrics)
+
+		assert.Panics(t, func() {
+			serve(r, "GET", "/panic")
+		})
+		body := serve(r, "GET", "/metrics").Body.String()
+		assert.Contains(t, body, `http_requests_in_flight{route="/panic",name="",method="GET"} 0`+"\n")
+	})
+}
@@ -0,0 +1,221 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+
+	pipelinecontract "github.com/gopi-frame/contract/pipeline"
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+)
+
+// TerminableMiddleware is a middleware which does some work after the response has been sent,
+// e.g. flushing audit logs or metrics.
+// Terminate is called with the request and the response in a new goroutine once the response has been written,
+// so that the client doesn't wait for it.
+// For constructable and parameterized middlewares, it is called on the instance which handled the request.
+type TerminableMiddleware interface {
+	routercontract.Middleware
+	Terminate(request *http.Request, response responseconstract.Responser)
+}
+
+// FallibleMiddleware is a constructable middleware whose construction can fail.
+// If Construct returns an error, the request is aborted with the response of the error handler,
+// see [Router.OnError].
+type FallibleMiddleware interface {
+	routercontract.Middleware
+	Construct(request *http.Request) error
+}
+
+// ShortCircuitMiddleware is a constructable middleware whose construction can end the request.
+// If Construct returns a response, the request is aborted with it.
+type ShortCircuitMiddleware interface {
+	routercontract.Middleware
+	Construct(request *http.Request) responseconstract.Responser
+}
+
+// constructorIndexCache caches the index of the Construct method of constructable middleware types.
+// It is shared by a router, its sub routers and their routes, and is safe for concurrent use.
+type constructorIndexCache struct {
+	mu      sync.RWMutex
+	indexes map[reflect.Type]int
+}
+
+func newConstructorIndexCache() *constructorIndexCache {
+	return &constructorIndexCache{
+		indexes: make(map[reflect.Type]int),
+	}
+}
+
+// warm resolves the constructor indexes of the given middlewares ahead of time,
+// so that serving requests only needs to read from the cache.
+func (c *constructorIndexCache) warm(middlewares ...routercontract.Middleware) {
+	for _, middleware := range middlewares {
+		switch middleware.(type) {
+		case ParameterizedMiddleware, routercontract.ConstructableMiddleware, FallibleMiddleware, ShortCircuitMiddleware:
+			c.index(reflect.Indirect(reflect.ValueOf(middleware)).Type())
+		}
+	}
+}
+
+func (c *constructorIndexCache) index(cmType reflect.Type) int {
+	c.mu.RLock()
+	index, ok := c.indexes[cmType]
+	c.mu.RUnlock()
+	if ok {
+		return index
+	}
+	method, _ := reflect.PointerTo(cmType).MethodByName("Construct")
+	c.mu.Lock()
+	c.indexes[cmType] = method.Index
+	c.mu.Unlock()
+	return method.Index
+}
+
+// pipes converts middlewares to pipes for the given request.
+// Every constructable or parameterized middleware is replaced with a new instance,
+// which gets services injected from the scope of the request if there is one, then is constructed with the request.
+// A middleware whose construction fails or returns a response is replaced with a pipe which aborts the request.
+func (c *constructorIndexCache) pipes(request *http.Request, middlewares []routercontract.Middleware) []pipelinecontract.Pipe[*http.Request, responseconstract.Responser] {
+	pipes := make([]pipelinecontract.Pipe[*http.Request, responseconstract.Responser], 0, len(middlewares))
+	for _, middleware := range middlewares {
+		var params []string
+		if am, ok := middleware.(*aliasedMiddleware); ok {
+			middleware, params = am.Middleware, am.params
+		}
+		args := []reflect.Value{reflect.ValueOf(request)}
+		switch middleware.(type) {
+		case ParameterizedMiddleware:
+			args = append(args, reflect.ValueOf(params))
+		case routercontract.ConstructableMiddleware, FallibleMiddleware, ShortCircuitMiddleware:
+		default:
+			pipes = append(pipes, middleware)
+			continue
+		}
+		cmType := reflect.Indirect(reflect.ValueOf(middleware)).Type()
+		instance := reflect.New(cmType)
+		if err := injectScope(request, instance); err != nil {
+			return append(pipes, &abortedPipe{response: errorResponse(request, err)})
+		}
+		if resp, aborted := callConstruct(request, instance.Method(c.index(cmType)), args...); aborted {
+			// the middlewares after it never run, so they are not constructed either
+			return append(pipes, &abortedPipe{response: resp})
+		}
+		pipes = append(pipes, instance.Interface().(routercontract.Middleware))
+	}
+	return pipes
+}
+
+// callConstruct calls the Construct method of a new instance of a controller or middleware.
+// It reports whether the request should be aborted, with the response to abort it with,
+// which is the response returned by Construct, or the response of the error handler for the error returned by Construct.
+func callConstruct(request *http.Request, construct reflect.Value, args ...reflect.Value) (responseconstract.Responser, bool) {
+	var out []reflect.Value
+	if construct.Type().IsVariadic() {
+		out = construct.CallSlice(args)
+	} else {
+		out = construct.Call(args)
+	}
+	if len(out) == 0 {
+		return nil, false
+	}
+	switch result := out[0].Interface().(type) {
+	case error:
+		return errorResponse(request, result), true
+	case responseconstract.Responser:
+		return result, true
+	}
+	return nil, false
+}
+
+// abortedPipe ends the request with the response, in place of a middleware whose construction aborted the request.
+type abortedPipe struct {
+	response responseconstract.Responser
+}
+
+func (a *abortedPipe) Handle(_ *http.Request, _ routercontract.Handler) responseconstract.Responser {
+	return a.response
+}
+
+// terminations are the terminations of requests which are running, see [terminate].
+var terminations sync.WaitGroup
+
+// terminate calls Terminate on every pipe which is a [TerminableMiddleware] in a new goroutine,
+// so that the response is sent without waiting for them, then calls done, e.g. to release what they may still use.
+// The request passed to them is not canceled when the response has been sent.
+func terminate(pipes []pipelinecontract.Pipe[*http.Request, responseconstract.Responser], request *http.Request, response responseconstract.Responser, done func()) {
+	var terminable []TerminableMiddleware
+	for _, pipe := range pipes {
+		if tm, ok := pipe.(TerminableMiddleware); ok {
+			terminable = append(terminable, tm)
+		}
+	}
+	if len(terminable) == 0 {
+		done()
+		return
+	}
+	request = request.WithContext(context.WithoutCancel(request.Context()))
+	terminations.Add(1)
+	go func() {
+		defer terminations.Done()
+		defer done()
+		for _, tm := range terminable {
+			tm.Terminate(request, response)
+		}
+	}()
+}
+
+// middlewareType returns the underlying type of the middleware, dereferencing pointers.
+func middlewareType(middleware any) reflect.Type {
+	if t, ok := middleware.(reflect.Type); ok {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		return t
+	}
+	return reflect.Indirect(reflect.ValueOf(middleware)).Type()
+}
+
+// matchMiddleware reports whether the middleware matches the target.
+// A target can be a middleware instance or a [reflect.Type], both matched by type,
+// or a string matched against the alias of the middleware,
+// or its type name with or without the package qualifier.
+func matchMiddleware(middleware routercontract.Middleware, target any) bool {
+	if am, ok := middleware.(*aliasedMiddleware); ok {
+		if name, ok := target.(string); ok && name == am.alias {
+			return true
+		}
+		middleware = am.Middleware
+	}
+	mt := middlewareType(middleware)
+	switch target := target.(type) {
+	case string:
+		return mt.Name() == target || mt.String() == target
+	case reflect.Type, routercontract.Middleware:
+		return mt == middlewareType(target)
+	}
+	return false
+}
+
+// withoutMiddlewares returns the middlewares which match none of the excluded targets.
+func withoutMiddlewares(middlewares []routercontract.Middleware, excluded []any) []routercontract.Middleware {
+	if len(excluded) == 0 {
+		return middlewares
+	}
+	result := make([]routercontract.Middleware, 0, len(middlewares))
+	for _, middleware := range middlewares {
+		var matched bool
+		for _, target := range excluded {
+			if matchMiddleware(middleware, target) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result = append(result, middleware)
+		}
+	}
+	return result
+}
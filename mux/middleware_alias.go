@@ -0,0 +1,190 @@
+package mux
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/exception"
+)
+
+// ParameterizedMiddleware is a middleware which accepts parameters from its alias, e.g. "throttle:60,1".
+// Like [routercontract.ConstructableMiddleware],
+// a new instance is created and constructed for every request.
+type ParameterizedMiddleware interface {
+	routercontract.Middleware
+	Construct(request *http.Request, params ...string)
+}
+
+// Named references a middleware alias or a middleware group registered on the router.
+// An alias can be followed by a colon and comma separated parameters, e.g. "throttle:60,1",
+// which are passed to the Construct method of a [ParameterizedMiddleware].
+// Names are resolved when the routes are validated or first served, so they can be registered in any order, see [Router.Validate].
+func Named(name string) routercontract.Middleware {
+	return &namedMiddleware{name: strings.TrimSpace(name)}
+}
+
+type namedMiddleware struct {
+	name string
+}
+
+func (n *namedMiddleware) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	panic(exception.NewArgumentException("middleware", n.name, "named middleware should be resolved by the router"))
+}
+
+// aliasedMiddleware is a middleware resolved from an alias, with the parameters it was referenced with.
+type aliasedMiddleware struct {
+	routercontract.Middleware
+
+	alias  string
+	params []string
+}
+
+// middlewareRegistry holds middleware aliases, groups and priorities.
+// It is shared by a router and its sub routers, and is safe for concurrent use.
+type middlewareRegistry struct {
+	mu       sync.RWMutex
+	aliases  map[string]routercontract.Middleware
+	groups   map[string][]routercontract.Middleware
+	priority []any
+	// version is incremented whenever the middlewares of the routers, groups or routes change.
+	version atomic.Uint64
+	// validation serializes the validations of the routes, see [Router.Validate].
+	validation sync.Mutex
+}
+
+func newMiddlewareRegistry() *middlewareRegistry {
+	return &middlewareRegistry{
+		aliases: make(map[string]routercontract.Middleware),
+		groups:  make(map[string][]routercontract.Middleware),
+	}
+}
+
+func (reg *middlewareRegistry) alias(name string, middleware routercontract.Middleware) {
+	if name == "" || strings.Contains(name, ":") {
+		panic(exception.NewArgumentException("name", name, "middleware alias should be non-empty and not contain ':'"))
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.aliases[name] = middleware
+	reg.changed()
+}
+
+func (reg *middlewareRegistry) group(name string, middlewares []routercontract.Middleware) {
+	if name == "" || strings.Contains(name, ":") {
+		panic(exception.NewArgumentException("name", name, "middleware group name should be non-empty and not contain ':'"))
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.groups[name] = append([]routercontract.Middleware(nil), middlewares...)
+	reg.changed()
+}
+
+// changed invalidates the middleware chains resolved for the routes.
+func (reg *middlewareRegistry) changed() {
+	reg.version.Add(1)
+}
+
+// resolve replaces every named middleware with the middlewares it references.
+// It fails if a name is not registered, if parameters are passed to a middleware which doesn't accept them,
+// or if a group references itself.
+func (reg *middlewareRegistry) resolve(middlewares []routercontract.Middleware) ([]routercontract.Middleware, error) {
+	var named bool
+	for _, middleware := range middlewares {
+		if _, ok := middleware.(*namedMiddleware); ok {
+			named = true
+			break
+		}
+	}
+	if !named {
+		return middlewares, nil
+	}
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.expand(middlewares, nil)
+}
+
+func (reg *middlewareRegistry) expand(middlewares []routercontract.Middleware, groups []string) ([]routercontract.Middleware, error) {
+	result := make([]routercontract.Middleware, 0, len(middlewares))
+	for _, middleware := range middlewares {
+		n, ok := middleware.(*namedMiddleware)
+		if !ok {
+			result = append(result, middleware)
+			continue
+		}
+		name, params, hasParams := strings.Cut(n.name, ":")
+		if alias, ok := reg.aliases[name]; ok {
+			am := &aliasedMiddleware{Middleware: alias, alias: name}
+			if hasParams {
+				for _, param := range strings.Split(params, ",") {
+					am.params = append(am.params, strings.TrimSpace(param))
+				}
+				if _, ok := alias.(ParameterizedMiddleware); !ok {
+					return nil, exception.NewArgumentException("middleware", n.name, "middleware does not accept parameters")
+				}
+			}
+			result = append(result, am)
+			continue
+		}
+		group, ok := reg.groups[name]
+		if !ok || hasParams {
+			return nil, exception.NewArgumentException("middleware", n.name, "middleware alias or group is not registered")
+		}
+		if slices.Contains(groups, name) {
+			return nil, exception.NewArgumentException("middleware", n.name, "middleware group references itself")
+		}
+		expanded, err := reg.expand(group, append(groups, name))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
+
+func (reg *middlewareRegistry) prioritize(targets []any) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.priority = append([]any(nil), targets...)
+	reg.changed()
+}
+
+// sort reorders the middlewares matching the priority list by their priority.
+// They take the positions previously held by prioritized middlewares,
+// while every other middleware keeps its position.
+func (reg *middlewareRegistry) sort(middlewares []routercontract.Middleware) []routercontract.Middleware {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	if len(reg.priority) == 0 {
+		return middlewares
+	}
+	var positions, priorities []int
+	for i, middleware := range middlewares {
+		for priority, target := range reg.priority {
+			if matchMiddleware(middleware, target) {
+				positions = append(positions, i)
+				priorities = append(priorities, priority)
+				break
+			}
+		}
+	}
+	if len(positions) < 2 {
+		return middlewares
+	}
+	prioritized := make([]int, len(positions))
+	for i := range prioritized {
+		prioritized[i] = i
+	}
+	slices.SortStableFunc(prioritized, func(a, b int) int {
+		return priorities[a] - priorities[b]
+	})
+	result := slices.Clone(middlewares)
+	for i, position := range positions {
+		result[position] = middlewares[positions[prioritized[i]]]
+	}
+	return result
+}
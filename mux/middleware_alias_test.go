@@ -0,0 +1,201 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+type throttleMiddleware struct {
+	params []string
+}
+
+func (t *throttleMiddleware) Construct(_ *http.Request, params ...string) {
+	t.params = params
+}
+
+func (t *throttleMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	resp := next(r)
+	resp.SetHeader("Throttle", strings.Join(t.params, ","))
+	return resp
+}
+
+func TestNamed(t *testing.T) {
+	handler := func(request *http.Request) responseconstract.Responser {
+		return response.New(200).JSON(map[string]any{"id": request.Context().Value(idKey)})
+	}
+
+	t.Run("alias", func(t *testing.T) {
+		r := New()
+		r.AliasMiddleware("auth", new(staticMiddleware))
+		r.Use(Named("auth"))
+		r.GET("/get", handler)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/get?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{"id": "1"}`, w.Body.String())
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+	})
+
+	t.Run("alias registered after use", func(t *testing.T) {
+		r := New()
+		r.GET("/get", handler).Use(Named("auth"))
+		r.AliasMiddleware("auth", new(nonStaticMiddleware))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/get", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 403, w.Code)
+		assert.Equal(t, "GET", w.Header().Get("Request-Method"))
+	})
+
+	t.Run("parameters", func(t *testing.T) {
+		r := New()
+		r.AliasMiddleware("throttle", new(throttleMiddleware))
+		r.GET("/get", handler).Use(Named("throttle:60, 1"))
+		r.GET("/default", handler).Use(Named("throttle"))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/get", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "60,1", w.Header().Get("Throttle"))
+
+		w2 := httptest.NewRecorder()
+		req2, _ := http.NewRequest("GET", "/default", nil)
+		r.ServeHTTP(w2, req2)
+		assert.Equal(t, 200, w2.Code)
+		assert.Equal(t, "", w2.Header().Get("Throttle"))
+	})
+
+	t.Run("group", func(t *testing.T) {
+		r := New()
+		r.AliasMiddleware("auth", new(staticMiddleware))
+		r.AliasMiddleware("throttle", new(throttleMiddleware))
+		r.MiddlewareGroup("web", Named("auth"))
+		r.MiddlewareGroup("api", Named("web"), Named("throttle:60,1"))
+		r.Group(&RouteGroup{Prefix: "/api"}, func(router routercontract.Router) {
+			router.Use(Named("api"))
+			router.GET("/get", handler)
+			router.GET("/public", handler).(*Route).WithoutMiddleware("auth")
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/get?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.JSONEq(t, `{"id": "1"}`, w.Body.String())
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+		assert.Equal(t, "60,1", w.Header().Get("Throttle"))
+
+		w2 := httptest.NewRecorder()
+		req2, _ := http.NewRequest("GET", "/api/public?id=1", nil)
+		r.ServeHTTP(w2, req2)
+		assert.Equal(t, 200, w2.Code)
+		assert.Equal(t, "", w2.Header().Get("Custom-Header"))
+		assert.Equal(t, "60,1", w2.Header().Get("Throttle"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		r := New()
+		assert.Panics(t, func() {
+			r.AliasMiddleware("auth:1", new(staticMiddleware))
+		})
+		r.AliasMiddleware("auth", new(staticMiddleware))
+		r.MiddlewareGroup("loop", Named("loop"))
+		for _, name := range []string{"unknown", "auth:1", "loop"} {
+			_, err := r.middlewareRegistry.resolve([]routercontract.Middleware{Named(name)})
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+type sessionMiddleware struct{}
+
+func (s *sessionMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	r.Header.Add("Order", "session")
+	return next(r)
+}
+
+type authMiddleware struct{}
+
+func (a *authMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	r.Header.Add("Order", "auth")
+	return next(r)
+}
+
+type logMiddleware struct{}
+
+func (l *logMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	r.Header.Add("Order", "log")
+	return next(r)
+}
+
+func TestRouter_MiddlewarePriority(t *testing.T) {
+	handler := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, strings.Join(request.Header.Values("Order"), ","))
+	}
+
+	r := New()
+	r.MiddlewarePriority(new(sessionMiddleware), "auth")
+	r.AliasMiddleware("auth", new(authMiddleware))
+	r.Use(Named("auth"))
+	r.Group(&RouteGroup{Prefix: "/group"}, func(router routercontract.Router) {
+		router.Use(new(logMiddleware), new(sessionMiddleware))
+		router.GET("/get", handler)
+	})
+	r.GET("/get", handler)
+	r.GET("/route", handler).Use(new(logMiddleware), new(sessionMiddleware))
+
+	for path, expected := range map[string]string{
+		"/group/get": "session,log,auth",
+		"/get":       "auth",
+		"/route":     "session,log,auth",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code, path)
+		assert.Equal(t, expected, w.Body.String(), path)
+	}
+}
+
+func TestRouter_Validate(t *testing.T) {
+	handler := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, strings.Join(request.Header.Values("Order"), ","))
+	}
+	r := New()
+	route := r.GET("/get", handler).Use(Named("auth")).(*Route)
+	r.GET("/other", handler).Use(Named("auth"))
+
+	err := r.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/get")
+		assert.Contains(t, err.Error(), "middleware alias or group is not registered")
+		assert.NotContains(t, err.Error(), "/other", "the same error is reported once")
+	}
+	assert.Equal(t, 500, serve(r, "GET", "/get").Code)
+	r.GET("/valid", handler)
+	assert.Equal(t, 200, serve(r, "GET", "/valid").Code, "the other routes are served")
+
+	r.AliasMiddleware("auth", new(authMiddleware))
+	assert.NoError(t, r.Validate())
+	w := serve(r, "GET", "/get")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "auth", w.Body.String())
+	assert.Same(t, route.resolveChain(), route.resolveChain(), "the chain is resolved once")
+
+	r.GET("/params", handler).Use(Named("auth:1"))
+	if err := r.Validate(); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/params")
+		assert.Contains(t, err.Error(), "does not accept parameters")
+	}
+}
@@ -0,0 +1,62 @@
+package mux
+
+import (
+	"net/http"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/pipeline"
+	"github.com/gopi-frame/response"
+)
+
+// FromHTTPMiddleware adapts a standard net/http middleware to a [routercontract.Middleware].
+//
+// The net/http middleware runs when the middleware is handled, and the rest of the chain runs when it calls the next handler,
+// so outer middlewares get the response once every inner middleware and the handler have run,
+// e.g. to time them or recover their panics.
+// What they write is buffered, then written when the returned response is served,
+// so the response can't be streamed to the client, e.g. by flushing it, nor hijacked.
+// Headers written by the net/http middleware or the rest of the chain take precedence over headers
+// set on the returned response by outer middlewares.
+func FromHTTPMiddleware(middleware func(http.Handler) http.Handler) routercontract.Middleware {
+	return &httpMiddleware{middleware: middleware}
+}
+
+type httpMiddleware struct {
+	middleware func(http.Handler) http.Handler
+}
+
+func (m *httpMiddleware) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	buffer := &bufferedWriter{header: make(http.Header)}
+	m.middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resp := next(req)
+		if resp == nil {
+			resp = response.New(http.StatusOK)
+		}
+		resp.ServeHTTP(w, req)
+	})).ServeHTTP(buffer, request)
+	return response.NewHandlerWrapper(buffer)
+}
+
+// ToHTTPMiddleware adapts middlewares to a standard net/http middleware,
+// which runs them in order around the next handler.
+// Constructable, parameterized and terminable middlewares behave as they do on a route,
+// but named middlewares can't be used, since they are resolved by a router.
+func ToHTTPMiddleware(middlewares ...routercontract.Middleware) func(http.Handler) http.Handler {
+	cache := newConstructorIndexCache()
+	cache.warm(middlewares...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			pipes := cache.pipes(req, middlewares)
+			resp := pipeline.New[*http.Request, responseconstract.Responser]().Send(req).Through(pipes...).Then(func(request *http.Request) responseconstract.Responser {
+				req = request
+				return response.NewHandlerWrapper(next)
+			})
+			if resp == nil {
+				resp = response.New(http.StatusOK)
+			}
+			resp.ServeHTTP(w, req)
+			terminate(pipes, req, resp, func() {})
+		})
+	}
+}
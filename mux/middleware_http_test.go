@@ -0,0 +1,156 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func httpAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Http-Middleware", "Before request")
+		if !r.URL.Query().Has("id") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), idKey, r.URL.Query().Get("id"))))
+	})
+}
+
+func TestFromHTTPMiddleware(t *testing.T) {
+	r := New()
+	r.Use(FromHTTPMiddleware(httpAuthMiddleware))
+	r.GET("/get", func(request *http.Request) responseconstract.Responser {
+		return response.New(201).JSON(map[string]any{"id": request.Context().Value(idKey)})
+	})
+	r.GET("/wrapped", func(request *http.Request) responseconstract.Responser {
+		return response.New(201).JSON(map[string]any{"id": request.Context().Value(idKey)})
+	}).Use(new(nonStaticMiddleware))
+	r.GET("/nil", func(request *http.Request) responseconstract.Responser {
+		return nil
+	})
+
+	t.Run("pass", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/get?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+		assert.JSONEq(t, `{"id": "1"}`, w.Body.String())
+		assert.Equal(t, "Before request", w.Header().Get("Http-Middleware"))
+	})
+
+	t.Run("block", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/get", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 401, w.Code)
+		assert.Equal(t, "Before request", w.Header().Get("Http-Middleware"))
+	})
+
+	t.Run("inner middleware", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/wrapped?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+		assert.Equal(t, "Before request", w.Header().Get("Http-Middleware"))
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+		assert.Equal(t, "GET", w.Header().Get("Request-Method"))
+	})
+
+	t.Run("nil response", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/nil?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+	})
+
+	t.Run("outer middleware", func(t *testing.T) {
+		r := New()
+		r.Use(new(staticMiddleware), FromHTTPMiddleware(httpAuthMiddleware))
+		r.GET("/get", func(request *http.Request) responseconstract.Responser {
+			return response.New(201)
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/get?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 201, w.Code)
+		assert.Equal(t, "Before request", w.Header().Get("Http-Middleware"))
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+	})
+}
+
+// timingMiddleware records whether the handler has run by the time the rest of the chain returns,
+// and recovers its panics.
+type timingMiddleware struct {
+	handled *bool
+}
+
+func (m *timingMiddleware) Handle(r *http.Request, next routercontract.Handler) (resp responseconstract.Responser) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			resp = response.New(http.StatusInternalServerError, recovered)
+		}
+	}()
+	resp = next(r)
+	return resp.SetHeader("Handled", strconv.FormatBool(*m.handled))
+}
+
+func TestFromHTTPMiddleware_synchronous(t *testing.T) {
+	var handled bool
+	r := New()
+	r.Use(&timingMiddleware{handled: &handled}, FromHTTPMiddleware(httpAuthMiddleware))
+	r.GET("/get", func(request *http.Request) responseconstract.Responser {
+		handled = true
+		return response.New(201, "handled")
+	})
+	r.GET("/panic", func(request *http.Request) responseconstract.Responser {
+		panic("boom")
+	})
+
+	w := serve(r, "GET", "/get?id=1")
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "handled", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Handled"))
+	assert.Equal(t, "Before request", w.Header().Get("Http-Middleware"))
+
+	w = serve(r, "GET", "/panic?id=1")
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "boom", w.Body.String())
+}
+
+func TestToHTTPMiddleware(t *testing.T) {
+	handler := ToHTTPMiddleware(new(nonStaticMiddleware), new(terminableMiddleware))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Handler", "Handled")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(r.Context().Value(idKey).(string)))
+	}))
+
+	t.Run("pass", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/?id=1", nil)
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, 202, w.Code)
+		assert.Equal(t, "1", w.Body.String())
+		assert.Equal(t, "Handled", w.Header().Get("Handler"))
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+		assert.Equal(t, "GET", w.Header().Get("Request-Method"))
+		assert.Equal(t, "GET /", <-terminatedRequests)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", nil)
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, 403, w.Code)
+		assert.Equal(t, "", w.Header().Get("Handler"))
+		assert.Equal(t, "POST", w.Header().Get("Request-Method"))
+		assert.Equal(t, "POST /", <-terminatedRequests)
+	})
+}
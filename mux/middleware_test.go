@@ -0,0 +1,116 @@
+package mux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+var errMissingTenant = errors.New("missing tenant")
+
+type tenantMiddleware struct {
+	tenant string
+}
+
+func (t *tenantMiddleware) Construct(r *http.Request) error {
+	t.tenant = r.Header.Get("Tenant")
+	if t.tenant == "" {
+		return errMissingTenant
+	}
+	return nil
+}
+
+func (t *tenantMiddleware) Handle(r *http.Request, next func(*http.Request) responseconstract.Responser) responseconstract.Responser {
+	return next(r).SetHeader("Tenant", t.tenant)
+}
+
+type apiKeyMiddleware struct{}
+
+func (a *apiKeyMiddleware) Construct(r *http.Request) responseconstract.Responser {
+	if r.Header.Get("Api-Key") == "" {
+		return response.New(http.StatusUnauthorized)
+	}
+	return nil
+}
+
+func (a *apiKeyMiddleware) Handle(r *http.Request, next func(*http.Request) responseconstract.Responser) responseconstract.Responser {
+	return next(r)
+}
+
+// unreachableMiddleware fails the test if it is constructed.
+type unreachableMiddleware struct{}
+
+func (u *unreachableMiddleware) Construct(r *http.Request) {
+	panic("constructed after the request was aborted")
+}
+
+func (u *unreachableMiddleware) Handle(r *http.Request, next func(*http.Request) responseconstract.Responser) responseconstract.Responser {
+	return next(r)
+}
+
+func TestFallibleMiddleware(t *testing.T) {
+	hello := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	}
+
+	t.Run("succeeded", func(t *testing.T) {
+		r := New()
+		r.GET("/hello", hello).Use(new(tenantMiddleware))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/hello", nil)
+		req.Header.Set("Tenant", "acme")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "acme", w.Header().Get("Tenant"))
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("failed without error handler", func(t *testing.T) {
+		r := New()
+		r.GET("/hello", hello).Use(new(tenantMiddleware), new(unreachableMiddleware))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/hello", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("failed with error handler", func(t *testing.T) {
+		r := New()
+		r.OnError(func(request *http.Request, err error) responseconstract.Responser {
+			if errors.Is(err, errMissingTenant) {
+				return response.New(http.StatusBadRequest, err.Error())
+			}
+			return nil
+		})
+		r.Group(&RouteGroup{Prefix: "/api"}, func(router routercontract.Router) {
+			router.Use(new(tenantMiddleware))
+			router.GET("/hello", hello)
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/hello", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "missing tenant", w.Body.String())
+	})
+}
+
+func TestShortCircuitMiddleware(t *testing.T) {
+	r := New()
+	r.Use(new(apiKeyMiddleware), new(unreachableMiddleware))
+	r.GET("/hello", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Body.String())
+}
@@ -0,0 +1,344 @@
+package mux
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TrailingSlashPolicy is how request paths which differ from a route path by a trailing slash are handled.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashStrict does not match them.
+	TrailingSlashStrict TrailingSlashPolicy = iota
+	// TrailingSlashRedirect redirects them to the route path.
+	TrailingSlashRedirect
+	// TrailingSlashMatch matches them.
+	TrailingSlashMatch
+)
+
+// DuplicateSlashPolicy is how request paths with duplicate slashes or dot segments, e.g. /a//b or /a/../b, are handled.
+type DuplicateSlashPolicy int
+
+const (
+	// DuplicateSlashRedirect redirects them to the clean path, as gorilla/mux does.
+	DuplicateSlashRedirect DuplicateSlashPolicy = iota
+	// DuplicateSlashMatch matches them as the clean path.
+	DuplicateSlashMatch
+	// DuplicateSlashStrict matches them as is only, so that a route matching them receives the dot segments.
+	DuplicateSlashStrict
+)
+
+// PathPolicy is how request paths which differ from route paths are matched.
+// The zero value matches paths as gorilla/mux does by default.
+type PathPolicy struct {
+	TrailingSlash    TrailingSlashPolicy
+	DuplicateSlashes DuplicateSlashPolicy
+	// CaseInsensitive matches the static parts of route paths regardless of case.
+	CaseInsensitive bool
+	// EncodedPath matches routes against the percent-encoded path,
+	// so that e.g. %2F in a path parameter is not a path separator, see [github.com/gorilla/mux.Router.UseEncodedPath].
+	// A group can't turn it off once its parent has turned it on.
+	EncodedPath bool
+	// NormalizeEncoding decodes percent-encoded unreserved characters, e.g. %7E, and upper-cases percent-encodings
+	// before matching, see RFC 3986 section 6.2.2.
+	NormalizeEncoding bool
+}
+
+// RouterOption configures a router created by [New].
+type RouterOption func(r *Router)
+
+// WithPathPolicy sets the path policy of the router, which groups inherit unless they have their own,
+// see [RouteGroup.PathPolicy].
+func WithPathPolicy(policy PathPolicy) RouterOption {
+	return func(r *Router) {
+		r.setPathPolicy(&policy)
+	}
+}
+
+func (r *Router) setPathPolicy(policy *PathPolicy) {
+	r.pathPolicy = policy
+	if policy.EncodedPath {
+		r.Router.UseEncodedPath()
+		r.encodedPath = true
+	}
+}
+
+// effectivePathPolicy returns the path policy of the router or the nearest of its parents.
+func (r *Router) effectivePathPolicy() PathPolicy {
+	for ; r != nil; r = r.parent {
+		if r.pathPolicy != nil {
+			return *r.pathPolicy
+		}
+	}
+	return PathPolicy{}
+}
+
+// pathNormalization is a normalization of the request path which matched a route.
+type pathNormalization struct {
+	encoding, clean, trailingSlash, caseFold bool
+}
+
+// allowedBy reports whether the policy allows the normalization, and whether the request should be redirected.
+func (n pathNormalization) allowedBy(policy PathPolicy) (allowed, redirect bool) {
+	switch {
+	case n.encoding && !policy.NormalizeEncoding,
+		n.clean && policy.DuplicateSlashes == DuplicateSlashStrict,
+		n.trailingSlash && policy.TrailingSlash == TrailingSlashStrict,
+		n.caseFold && !policy.CaseInsensitive:
+		return false, false
+	}
+	redirect = n.clean && policy.DuplicateSlashes == DuplicateSlashRedirect ||
+		n.trailingSlash && policy.TrailingSlash == TrailingSlashRedirect
+	return true, redirect
+}
+
+// normalizePath matches the path of a request against the routes, as their path policies allow,
+// if the path isn't clean, before matching, or if it matched no route, see [Router.notFoundHandler].
+// It returns the request with the path of the route it matches, or nil if there is none,
+// and reports whether the request has been redirected instead.
+func (r *Router) normalizePath(w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	escaped := req.URL.EscapedPath()
+	encoded := escaped
+	if r.normalizeEncoding {
+		encoded = normalizeEncoding(escaped)
+	}
+	clean := cleanPath(encoded)
+	cleaned := clean != encoded
+	if !r.encodedPath {
+		// routes match the decoded path, whose dot segments and slashes may be percent-encoded
+		clean, cleaned = r.cleanRequestPath(req)
+	}
+	candidates := []struct {
+		path          string
+		normalization pathNormalization
+	}{
+		{escaped, pathNormalization{}},
+		{encoded, pathNormalization{encoding: true}},
+		{clean, pathNormalization{encoding: encoded != escaped, clean: true}},
+		{toggleTrailingSlash(clean), pathNormalization{encoding: encoded != escaped, clean: cleaned, trailingSlash: true}},
+	}
+	seen := make(map[string]bool, len(candidates))
+	matched := false
+	for i, c := range candidates {
+		if seen[c.path] {
+			continue
+		}
+		seen[c.path] = true
+		probe := withPath(req, c.path)
+		var route *Route
+		// the request path as is matches no route, but may match a case-insensitive one
+		if i > 0 {
+			route = r.matchAny(probe)
+		}
+		if route == nil {
+			if route, probe = r.matchCaseInsensitive(probe); route == nil {
+				continue
+			}
+			c.normalization.caseFold = true
+		}
+		matched = true
+		allowed, redirect := c.normalization.allowedBy(route.pathPolicy)
+		if !allowed {
+			continue
+		}
+		if redirect {
+			redirectTo(w, req, probe.URL)
+			return nil, true
+		}
+		return probe, false
+	}
+	// gorilla/mux redirects to the clean path even if it matches no route,
+	// unless the path as is matches a route whose policy keeps it
+	clean, dirty := r.cleanRequestPath(req)
+	if matched || !dirty || r.effectivePathPolicy().DuplicateSlashes != DuplicateSlashRedirect {
+		return nil, false
+	}
+	if route := r.matchAny(req); route != nil && route.pathPolicy.DuplicateSlashes != DuplicateSlashRedirect {
+		return nil, false
+	}
+	redirectTo(w, req, withPath(req, clean).URL)
+	return nil, true
+}
+
+// cleanRequestPath returns the clean form of the request path which routes match, percent-encoded,
+// and reports whether the request path isn't clean, see [cleanPath].
+// Like gorilla/mux, the decoded path is cleaned unless routes match the percent-encoded path.
+func (r *Router) cleanRequestPath(req *http.Request) (string, bool) {
+	if r.encodedPath {
+		escaped := req.URL.EscapedPath()
+		clean := cleanPath(escaped)
+		return clean, clean != escaped
+	}
+	clean := cleanPath(req.URL.Path)
+	return (&url.URL{Path: clean}).EscapedPath(), clean != req.URL.Path
+}
+
+// matchAny returns the route matching the request with its method, or else with any method, or nil if there is none.
+// Each method is matched once, whatever the number of routes.
+func (r *Router) matchAny(req *http.Request) *Route {
+	probe := req.Clone(req.Context())
+	if route := r.matchProbe(probe, req.Method); route != nil {
+		return route
+	}
+	for _, method := range r.methods {
+		if route := r.matchProbe(probe, method); route != nil {
+			return route
+		}
+	}
+	return nil
+}
+
+// matchCaseInsensitive returns the case-insensitive route matching the request regardless of the case of its path,
+// with the request rewritten to the path of the route.
+func (r *Router) matchCaseInsensitive(req *http.Request) (*Route, *http.Request) {
+	for _, route := range r.caseInsensitiveRoutes {
+		p := req.URL.Path
+		if route.router.encodedPath {
+			p = req.URL.EscapedPath()
+		}
+		values := route.caseInsensitivePath.FindStringSubmatch(p)
+		if values == nil {
+			continue
+		}
+		names, _ := route.GetVarNames()
+		pairs := make([]string, 0, 2*(len(values)-1))
+		for i, value := range values[1:] {
+			pairs = append(pairs, names[route.hostVars+i], value)
+		}
+		u, err := route.URLPath(pairs...)
+		if err != nil {
+			continue
+		}
+		probe := withPath(req, u.EscapedPath())
+		if r.matchAny(probe) == route {
+			return route, probe
+		}
+	}
+	return nil, nil
+}
+
+// compileCaseInsensitivePath prepares the route for case-insensitive matching.
+// It reports whether the route can be matched regardless of case, which requires methods and a path.
+func (r *Route) compileCaseInsensitivePath() bool {
+	methods, err := r.GetMethods()
+	pathRegexp, perr := r.GetPathRegexp()
+	if err != nil || len(methods) == 0 || perr != nil {
+		return false
+	}
+	r.caseInsensitivePath = regexp.MustCompile("(?i)" + pathRegexp)
+	if hostTemplate, err := r.GetHostTemplate(); err == nil {
+		r.hostVars = countVars(hostTemplate)
+	}
+	return true
+}
+
+// countVars returns the number of variables in the route template.
+func countVars(template string) int {
+	count, depth := 0, 0
+	for i := 0; i < len(template); i++ {
+		switch template[i] {
+		case '{':
+			if depth == 0 {
+				count++
+			}
+			depth++
+		case '}':
+			depth--
+		}
+	}
+	return count
+}
+
+// withPath returns a copy of the request with the percent-encoded path.
+func withPath(req *http.Request, escaped string) *http.Request {
+	u := *req.URL
+	if p, err := url.PathUnescape(escaped); err == nil {
+		u.Path, u.RawPath = p, ""
+		if u.EscapedPath() != escaped {
+			u.RawPath = escaped
+		}
+	}
+	rewritten := req.Clone(req.Context())
+	rewritten.URL = &u
+	return rewritten
+}
+
+// redirectTo redirects the request to the URL, preserving the method of requests other than GET and HEAD.
+func redirectTo(w http.ResponseWriter, req *http.Request, u *url.URL) {
+	status := http.StatusMovedPermanently
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+	w.Header().Set("Location", u.String())
+	w.WriteHeader(status)
+}
+
+// cleanPath returns the canonical form of the path, without duplicate slashes and dot segments,
+// keeping its trailing slash, as gorilla/mux does.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+func toggleTrailingSlash(p string) string {
+	if p == "/" {
+		return p
+	}
+	if strings.HasSuffix(p, "/") {
+		return strings.TrimSuffix(p, "/")
+	}
+	return p + "/"
+}
+
+// normalizeEncoding decodes the percent-encoded unreserved characters of the path and upper-cases the other encodings.
+func normalizeEncoding(escaped string) string {
+	if !strings.Contains(escaped, "%") {
+		return escaped
+	}
+	var b strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '%' || i+2 >= len(escaped) || !isHex(escaped[i+1]) || !isHex(escaped[i+2]) {
+			b.WriteByte(escaped[i])
+			continue
+		}
+		c := unhex(escaped[i+1])<<4 | unhex(escaped[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(escaped[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	}
+	return c - 'a' + 10
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,201 @@
+package mux
+
+import (
+	"net/http"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func pathHandler(request *http.Request) responseconstract.Responser {
+	return response.New(200, request.URL.EscapedPath()+" "+mux.Vars(request)["id"])
+}
+
+func TestPathPolicy(t *testing.T) {
+	r := New()
+	r.GET("/users/{id}", pathHandler)
+	r.POST("/users", pathHandler)
+	r.Group(&RouteGroup{Prefix: "/redirect", PathPolicy: &PathPolicy{TrailingSlash: TrailingSlashRedirect}}, func(router routercontract.Router) {
+		router.GET("/users", pathHandler)
+		router.POST("/users", pathHandler)
+	})
+	r.Group(&RouteGroup{Prefix: "/match", PathPolicy: &PathPolicy{TrailingSlash: TrailingSlashMatch, DuplicateSlashes: DuplicateSlashMatch}}, func(router routercontract.Router) {
+		router.GET("/users/", pathHandler)
+	})
+	r.Group(&RouteGroup{Prefix: "/strict", PathPolicy: &PathPolicy{DuplicateSlashes: DuplicateSlashStrict}}, func(router routercontract.Router) {
+		router.GET("/users", pathHandler)
+	})
+
+	for _, c := range []struct {
+		method, path string
+		code         int
+		location     string
+		body         string
+	}{
+		{"GET", "/users/1", 200, "", "/users/1 1"},
+		{"GET", "/users/1/", 404, "", ""},
+		{"GET", "/users//1", 301, "/users/1", ""},
+		{"GET", "/users/../users/1?q=1", 301, "/users/1?q=1", ""},
+		{"GET", "/missing//path", 301, "/missing/path", ""},
+		{"POST", "/users/", 404, "", ""},
+		{"GET", "/redirect/users/", 301, "/redirect/users", ""},
+		{"POST", "/redirect/users/", 308, "/redirect/users", ""},
+		{"GET", "/redirect//users/", 301, "/redirect/users", ""},
+		{"GET", "/match/users/", 200, "", "/match/users/ "},
+		{"GET", "/match/users", 200, "", "/match/users/ "},
+		{"GET", "/match//users", 200, "", "/match/users/ "},
+		{"GET", "/strict/users", 200, "", "/strict/users "},
+		{"GET", "/strict//users", 404, "", ""},
+	} {
+		w := serve(r, c.method, c.path)
+		assert.Equal(t, c.code, w.Code, c.method+" "+c.path)
+		assert.Equal(t, c.location, w.Header().Get("Location"), c.method+" "+c.path)
+		if c.body != "" {
+			assert.Equal(t, c.body, w.Body.String(), c.method+" "+c.path)
+		}
+	}
+}
+
+func TestPathPolicy_caseInsensitive(t *testing.T) {
+	r := New(WithPathPolicy(PathPolicy{CaseInsensitive: true, TrailingSlash: TrailingSlashRedirect}))
+	r.GET("/Users/{id}", pathHandler)
+	r.Group(&RouteGroup{Prefix: "/strict", PathPolicy: &PathPolicy{}}, func(router routercontract.Router) {
+		router.GET("/users", pathHandler)
+	})
+
+	for _, c := range []struct {
+		path     string
+		code     int
+		location string
+		body     string
+	}{
+		{"/users/AbC", 200, "", "/Users/AbC AbC"},
+		{"/USERS/1", 200, "", "/Users/1 1"},
+		{"/USERS/1/", 301, "/Users/1", ""},
+		{"/strict/users", 200, "", "/strict/users "},
+		{"/STRICT/users", 404, "", ""},
+	} {
+		w := serve(r, "GET", c.path)
+		assert.Equal(t, c.code, w.Code, c.path)
+		assert.Equal(t, c.location, w.Header().Get("Location"), c.path)
+		if c.body != "" {
+			assert.Equal(t, c.body, w.Body.String(), c.path)
+		}
+	}
+}
+
+func TestPathPolicy_encoding(t *testing.T) {
+	r := New(WithPathPolicy(PathPolicy{EncodedPath: true, NormalizeEncoding: true}))
+	r.GET("/files/{id}", pathHandler)
+	r.GET("/~user", pathHandler)
+
+	for _, c := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/a%2Fb", 200, "/files/a%2Fb a%2Fb"},
+		{"/%7Euser", 200, "/~user "},
+		{"/%7euser", 200, "/~user "},
+		{"/files/a/b", 404, ""},
+	} {
+		w := serve(r, "GET", c.path)
+		assert.Equal(t, c.code, w.Code, c.path)
+		if c.body != "" {
+			assert.Equal(t, c.body, w.Body.String(), c.path)
+		}
+	}
+
+	strict := New(WithPathPolicy(PathPolicy{EncodedPath: true}))
+	strict.GET("/~user", pathHandler)
+	assert.Equal(t, 404, serve(strict, "GET", "/%7Euser").Code)
+}
+
+func TestPathPolicy_onNotFound(t *testing.T) {
+	r := New(WithPathPolicy(PathPolicy{EncodedPath: true, NormalizeEncoding: true}))
+	r.GET("/users/{id}", pathHandler)
+	r.GET("/~user", pathHandler)
+	r.Group(&RouteGroup{Prefix: "/redirect", PathPolicy: &PathPolicy{TrailingSlash: TrailingSlashRedirect}}, func(router routercontract.Router) {
+		router.GET("/users", pathHandler)
+	})
+	r.Group(&RouteGroup{Prefix: "/match", PathPolicy: &PathPolicy{TrailingSlash: TrailingSlashMatch, DuplicateSlashes: DuplicateSlashMatch}}, func(router routercontract.Router) {
+		router.GET("/users/", pathHandler)
+	})
+	r.Group(&RouteGroup{Prefix: "/strict", PathPolicy: &PathPolicy{DuplicateSlashes: DuplicateSlashStrict}}, func(router routercontract.Router) {
+		router.GET("/users", pathHandler)
+	})
+	r.Group(&RouteGroup{Prefix: "/insensitive", PathPolicy: &PathPolicy{CaseInsensitive: true}}, func(router routercontract.Router) {
+		router.GET("/Users/{id}", pathHandler)
+	})
+	r.OnNotFound(func(request *http.Request) responseconstract.Responser {
+		return response.New(404, "custom")
+	})
+
+	for _, c := range []struct {
+		path     string
+		code     int
+		location string
+		body     string
+	}{
+		{"/users/1", 200, "", "/users/1 1"},
+		{"/users//1", 301, "/users/1", ""},
+		{"/missing//path", 301, "/missing/path", ""},
+		{"/users/1/", 404, "", "custom"},
+		{"/%7Euser", 200, "", "/~user "},
+		{"/redirect/users/", 301, "/redirect/users", ""},
+		{"/match//users", 200, "", "/match/users/ "},
+		{"/strict//users", 404, "", "custom"},
+		{"/insensitive/USERS/1", 200, "", "/insensitive/Users/1 1"},
+		{"/USERS/1", 404, "", "custom"},
+		{"/missing", 404, "", "custom"},
+	} {
+		w := serve(r, "GET", c.path)
+		assert.Equal(t, c.code, w.Code, c.path)
+		assert.Equal(t, c.location, w.Header().Get("Location"), c.path)
+		if c.body != "" {
+			assert.Equal(t, c.body, w.Body.String(), c.path)
+		}
+	}
+}
+
+func TestPathPolicy_dotSegments(t *testing.T) {
+	r := New()
+	r.GET("/files/{path:.*}", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, mux.Vars(request)["path"])
+	})
+	r.Group(&RouteGroup{Prefix: "/raw", PathPolicy: &PathPolicy{DuplicateSlashes: DuplicateSlashStrict}}, func(router routercontract.Router) {
+		router.GET("/{path:.*}", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, mux.Vars(request)["path"])
+		})
+	})
+	r.Group(&RouteGroup{Prefix: "/match", PathPolicy: &PathPolicy{DuplicateSlashes: DuplicateSlashMatch}}, func(router routercontract.Router) {
+		router.GET("/{path:.*}", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, mux.Vars(request)["path"])
+		})
+	})
+
+	for _, c := range []struct {
+		path     string
+		code     int
+		location string
+		body     string
+	}{
+		{"/files/a/b", 200, "", "a/b"},
+		{"/files/../../etc/passwd", 301, "/etc/passwd", ""},
+		{"/files/..%2F..%2Fetc%2Fpasswd", 301, "/etc/passwd", ""},
+		{"/files/a/../b", 301, "/files/b", ""},
+		{"/raw/a/../b", 200, "", "a/../b"},
+		{"/match/a/../b", 200, "", "b"},
+	} {
+		w := serve(r, "GET", c.path)
+		assert.Equal(t, c.code, w.Code, c.path)
+		assert.Equal(t, c.location, w.Header().Get("Location"), c.path)
+		if c.body != "" {
+			assert.Equal(t, c.body, w.Body.String(), c.path)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package mux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+)
+
+// DefaultRequestIDHeader is the header holding the request ID, unless configured otherwise.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength is the length above which incoming request IDs are replaced with generated ones.
+const maxRequestIDLength = 128
+
+// RequestIDOption configures the middleware created by [RequestID].
+type RequestIDOption func(middleware *RequestIDMiddleware)
+
+// RequestIDHeader sets the header holding the request ID, which defaults to [DefaultRequestIDHeader].
+func RequestIDHeader(header string) RequestIDOption {
+	return func(middleware *RequestIDMiddleware) {
+		middleware.header = header
+	}
+}
+
+// RequestIDGenerator sets the generator of the request IDs, which defaults to [NewUUID].
+func RequestIDGenerator(generator func() string) RequestIDOption {
+	return func(middleware *RequestIDMiddleware) {
+		middleware.generator = generator
+	}
+}
+
+// RequestID creates a middleware which reads the request ID from the request header,
+// or generates one if the header is missing or invalid,
+// then stores it in the request context and echoes it in the response header.
+//
+// The request ID is available with [RequestIDFromContext],
+// to loggers through [RequestIDLogHandler], and to outgoing requests through [RequestIDTransport].
+func RequestID(options ...RequestIDOption) *RequestIDMiddleware {
+	middleware := &RequestIDMiddleware{
+		header:    DefaultRequestIDHeader,
+		generator: NewUUID,
+	}
+	for _, option := range options {
+		option(middleware)
+	}
+	return middleware
+}
+
+// RequestIDMiddleware propagates request IDs, see [RequestID].
+type RequestIDMiddleware struct {
+	header    string
+	generator func() string
+}
+
+func (m *RequestIDMiddleware) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	id := request.Header.Get(m.header)
+	if !validRequestID(id) {
+		id = m.generator()
+		request.Header.Set(m.header, id)
+	}
+	resp := next(request.WithContext(context.WithValue(request.Context(), requestIDKey{}, requestID{id: id, header: m.header})))
+	if resp == nil {
+		resp = response.New(http.StatusOK)
+	}
+	return resp.SetHeader(m.header, id)
+}
+
+// validRequestID reports whether the incoming request ID can be used as is,
+// which keeps arbitrary content out of logs and response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type requestIDKey struct{}
+
+type requestID struct {
+	id     string
+	header string
+}
+
+// RequestIDFromContext returns the request ID stored in the context, or an empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	return rid.id
+}
+
+// WithRequestID returns a copy of the context which holds the request ID,
+// e.g. for background jobs which continue a request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID{id: id, header: DefaultRequestIDHeader})
+}
+
+// RequestIDLogHandler wraps the log handler to add the request ID of the context of every record,
+// as the request_id attribute, e.g. when logging with [slog.Logger.InfoContext],
+// unless the record already has one, e.g. records of [AccessLog].
+func RequestIDLogHandler(handler slog.Handler) slog.Handler {
+	return &requestIDLogHandler{Handler: handler}
+}
+
+type requestIDLogHandler struct {
+	slog.Handler
+}
+
+func (h *requestIDLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		var found bool
+		record.Attrs(func(attr slog.Attr) bool {
+			found = attr.Key == "request_id"
+			return !found
+		})
+		if !found {
+			record.AddAttrs(slog.String("request_id", id))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *requestIDLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDLogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestIDLogHandler) WithGroup(name string) slog.Handler {
+	return &requestIDLogHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// RequestIDTransport wraps the transport to send the request ID of the context of outgoing requests,
+// in the header it has been received with. If base is nil, [http.DefaultTransport] is used.
+func RequestIDTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &requestIDTransport{base: base}
+}
+
+// RequestIDClient returns a client which sends the request ID of the context with every request,
+// including the requests not created with the context.
+func RequestIDClient(ctx context.Context) *http.Client {
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	return &http.Client{Transport: &requestIDTransport{base: http.DefaultTransport, fallback: rid}}
+}
+
+type requestIDTransport struct {
+	base     http.RoundTripper
+	fallback requestID
+}
+
+func (t *requestIDTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	rid, ok := request.Context().Value(requestIDKey{}).(requestID)
+	if !ok {
+		rid = t.fallback
+	}
+	if rid.id != "" && request.Header.Get(rid.header) == "" {
+		// a RoundTripper must not modify the request
+		request = request.Clone(request.Context())
+		request.Header.Set(rid.header, rid.id)
+	}
+	return t.base.RoundTrip(request)
+}
+
+// NewUUID generates a random version 4 UUID, e.g. 0b9a7c1e-5f4d-4b6a-9c3e-2d1f0e8a7b6c.
+func NewUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// crockford is the Crockford's base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID, which sorts by creation time, e.g. 01ARZ3NDEKTSV4RRFFQ69G5FAV.
+func NewULID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	_, _ = rand.Read(b[6:])
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	// 26 characters of 5 bits encode the 128 bits, starting with the 3 most significant ones
+	for i := 25; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
@@ -0,0 +1,106 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	r := New()
+	r.Use(RequestID())
+	r.GET("/id", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, RequestIDFromContext(request.Context()))
+	})
+
+	t.Run("incoming", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/id", nil)
+		req.Header.Set("X-Request-Id", "abc-123")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, "abc-123", w.Body.String())
+		assert.Equal(t, "abc-123", w.Header().Get("X-Request-Id"))
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		for _, id := range []string{"", "bad\nid", strings.Repeat("a", 129)} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/id", nil)
+			req.Header["X-Request-Id"] = []string{id}
+			r.ServeHTTP(w, req)
+			assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, w.Body.String())
+			assert.Equal(t, w.Body.String(), w.Header().Get("X-Request-Id"))
+		}
+	})
+
+	t.Run("custom header and generator", func(t *testing.T) {
+		r := New()
+		r.Use(RequestID(RequestIDHeader("X-Correlation-Id"), RequestIDGenerator(NewULID)))
+		r.GET("/id", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, RequestIDFromContext(request.Context()))
+		})
+		w := serve(r, "GET", "/id")
+		assert.Regexp(t, `^[0-9A-HJKMNP-TV-Z]{26}$`, w.Body.String())
+		assert.Equal(t, w.Body.String(), w.Header().Get("X-Correlation-Id"))
+	})
+}
+
+func TestNewULID(t *testing.T) {
+	a, b := NewULID(), NewULID()
+	assert.NotEqual(t, a, b)
+	assert.Regexp(t, regexp.MustCompile(`^[0-7]`), a)
+	// the timestamp comes first, so ULIDs of different milliseconds sort by creation time
+	assert.LessOrEqual(t, a[:10], b[:10])
+}
+
+func TestRequestIDLogHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(RequestIDLogHandler(slog.NewTextHandler(buf, nil))).With("service", "test")
+	logger.InfoContext(WithRequestID(context.Background(), "abc"), "hello")
+	logger.InfoContext(WithRequestID(context.Background(), "abc"), "explicit", "request_id", "def")
+	logger.InfoContext(context.Background(), "none")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Contains(t, lines[0], "service=test request_id=abc")
+	assert.Equal(t, 1, strings.Count(lines[1], "request_id="))
+	assert.Contains(t, lines[1], "request_id=def")
+	assert.NotContains(t, lines[2], "request_id")
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("X-Correlation-Id"))
+	}))
+	defer server.Close()
+
+	r := New()
+	r.Use(RequestID(RequestIDHeader("X-Correlation-Id")))
+	r.GET("/proxy", func(request *http.Request) responseconstract.Responser {
+		client := &http.Client{Transport: RequestIDTransport(nil)}
+		outgoing, _ := http.NewRequestWithContext(request.Context(), "GET", server.URL, nil)
+		resp, err := client.Do(outgoing)
+		if assert.NoError(t, err) {
+			_ = resp.Body.Close()
+		}
+		resp, err = RequestIDClient(request.Context()).Get(server.URL)
+		if assert.NoError(t, err) {
+			_ = resp.Body.Close()
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/proxy", nil)
+	req.Header.Set("X-Correlation-Id", "abc")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, []string{"abc", "abc"}, received)
+}
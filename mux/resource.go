@@ -0,0 +1,216 @@
+package mux
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/exception"
+)
+
+// resourceActions are the conventional actions of a resource controller, in registration order,
+// so that /create is registered before /{parameter}.
+var resourceActions = []struct {
+	action  string
+	method  string
+	methods []string
+	member  bool
+	suffix  string
+}{
+	{"index", "Index", []string{http.MethodGet}, false, ""},
+	{"create", "Create", []string{http.MethodGet}, false, "/create"},
+	{"store", "Store", []string{http.MethodPost}, false, ""},
+	{"show", "Show", []string{http.MethodGet}, true, ""},
+	{"edit", "Edit", []string{http.MethodGet}, true, "/edit"},
+	{"update", "Update", []string{http.MethodPut, http.MethodPatch}, true, ""},
+	{"destroy", "Destroy", []string{http.MethodDelete}, true, ""},
+}
+
+// ResourceOption configures a resource registered with [Router.Resource].
+type ResourceOption func(options *resourceOptions)
+
+type resourceOptions struct {
+	only      []string
+	except    []string
+	shallow   bool
+	parameter string
+	name      string
+}
+
+// OnlyActions registers only the given actions of the resource, e.g. "index" and "show".
+func OnlyActions(actions ...string) ResourceOption {
+	return func(options *resourceOptions) {
+		options.only = append(options.only, actions...)
+	}
+}
+
+// ExceptActions registers every action of the resource but the given ones.
+func ExceptActions(actions ...string) ResourceOption {
+	return func(options *resourceOptions) {
+		options.except = append(options.except, actions...)
+	}
+}
+
+// ShallowResource registers the member actions of a nested resource without the parent resources,
+// e.g. /photos/{photo} instead of /users/{user}/photos/{photo}.
+// Other segments are kept, e.g. /admin/photos/{photo} for /admin/users/{user}/photos.
+func ShallowResource() ResourceOption {
+	return func(options *resourceOptions) {
+		options.shallow = true
+	}
+}
+
+// ResourceParameter sets the name of the path parameter of the resource,
+// which defaults to the singular form of the last path segment, e.g. photo for /photos.
+func ResourceParameter(parameter string) ResourceOption {
+	return func(options *resourceOptions) {
+		options.parameter = parameter
+	}
+}
+
+// ResourceName sets the prefix of the route names of the resource,
+// which defaults to the static path segments joined with dots, e.g. users.photos for /users/{user}/photos.
+func ResourceName(name string) ResourceOption {
+	return func(options *resourceOptions) {
+		options.name = name
+	}
+}
+
+// Resource is the set of routes registered for a resource controller.
+type Resource struct {
+	routes map[string]*Route
+}
+
+// Route returns the route of the action, or nil if the action isn't registered.
+func (r *Resource) Route(action string) *Route {
+	return r.routes[action]
+}
+
+// Use appends middlewares to every route of the resource.
+func (r *Resource) Use(middlewares ...routercontract.Middleware) *Resource {
+	for _, route := range r.routes {
+		route.Use(middlewares...)
+	}
+	return r
+}
+
+// WithoutMiddleware excludes middlewares from every route of the resource.
+func (r *Resource) WithoutMiddleware(middlewares ...any) *Resource {
+	for _, route := range r.routes {
+		route.WithoutMiddleware(middlewares...)
+	}
+	return r
+}
+
+// Resource registers the conventional routes of a resource controller,
+// for each of its methods named after an action, with the signature func(*http.Request) Responser:
+//
+//	GET       /photos                 Index    photos.index
+//	GET       /photos/create          Create   photos.create
+//	POST      /photos                 Store    photos.store
+//	GET       /photos/{photo}         Show     photos.show
+//	GET       /photos/{photo}/edit    Edit     photos.edit
+//	PUT/PATCH /photos/{photo}         Update   photos.update
+//	DELETE    /photos/{photo}         Destroy  photos.destroy
+//
+// The path can contain parameters of parent resources, e.g. /users/{user}/photos.
+// If the controller has a Construct method, a new instance is created for every request,
+// like with [Action].
+func (r *Router) Resource(path string, controller any, opts ...ResourceOption) *Resource {
+	options := new(resourceOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	path = "/" + strings.Trim(path, "/")
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if !strings.HasPrefix(segment, "{") {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 || strings.HasSuffix(path, "}") {
+		panic(exception.NewArgumentException("path", path, "resource path should end with a static segment"))
+	}
+	last := segments[len(segments)-1]
+	if options.parameter == "" {
+		options.parameter = singular(last)
+	}
+	if options.name == "" {
+		options.name = strings.Join(segments, ".")
+	}
+	memberPath := path + "/{" + options.parameter + "}"
+	if options.shallow {
+		memberPath = shallowPath(path) + "/{" + options.parameter + "}"
+	}
+
+	controllerType := reflect.TypeOf(controller)
+	for _, action := range options.only {
+		if _, ok := controllerType.MethodByName(actionMethod(action)); !ok {
+			panic(exception.NewArgumentException("action", action, "controller has no method for the action"))
+		}
+	}
+
+	resource := &Resource{routes: make(map[string]*Route)}
+	for _, a := range resourceActions {
+		if (len(options.only) > 0 && !slices.Contains(options.only, a.action)) || slices.Contains(options.except, a.action) {
+			continue
+		}
+		if _, ok := controllerType.MethodByName(a.method); !ok {
+			continue
+		}
+		handler, err := actionHandler(controller, a.method)
+		if err != nil {
+			panic(err)
+		}
+		actionPath := path
+		if a.member {
+			actionPath = memberPath
+		}
+		route := r.Route(a.methods, actionPath+a.suffix, handler).Name(options.name + "." + a.action).(*Route)
+		route.controller, route.action = controllerType, a.method
+		resource.routes[a.action] = route
+	}
+	return resource
+}
+
+// shallowPath returns the path without the parameters of parent resources and the segments of the parent resources
+// they follow, e.g. /admin/photos for /admin/users/{user}/photos.
+func shallowPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	kept := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") {
+			continue
+		}
+		// the static segment before a parameter is the parent resource
+		if i+1 < len(segments) && strings.HasPrefix(segments[i+1], "{") {
+			continue
+		}
+		kept = append(kept, segment)
+	}
+	return "/" + strings.Join(kept, "/")
+}
+
+func actionMethod(action string) string {
+	for _, a := range resourceActions {
+		if a.action == action {
+			return a.method
+		}
+	}
+	return action
+}
+
+// singular returns a naive singular form of an English plural noun.
+func singular(noun string) string {
+	switch {
+	case strings.HasSuffix(noun, "ies") && len(noun) > 3:
+		return strings.TrimSuffix(noun, "ies") + "y"
+	case strings.HasSuffix(noun, "sses"), strings.HasSuffix(noun, "xes"), strings.HasSuffix(noun, "ches"), strings.HasSuffix(noun, "shes"):
+		return strings.TrimSuffix(noun, "es")
+	case strings.HasSuffix(noun, "s") && !strings.HasSuffix(noun, "ss"):
+		return strings.TrimSuffix(noun, "s")
+	}
+	return noun
+}
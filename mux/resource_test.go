@@ -0,0 +1,201 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	"github.com/gopi-frame/response"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type photoController struct {
+	method string
+}
+
+func (p *photoController) Construct(r *http.Request) {
+	p.method = r.Method
+}
+
+func (p *photoController) Index(r *http.Request) responseconstract.Responser {
+	return response.New(200, p.method+" index "+mux.Vars(r)["user"])
+}
+
+func (p *photoController) Create(r *http.Request) responseconstract.Responser {
+	return response.New(200, p.method+" create")
+}
+
+func (p *photoController) Store(r *http.Request) responseconstract.Responser {
+	return response.New(201, p.method+" store")
+}
+
+func (p *photoController) Show(r *http.Request) responseconstract.Responser {
+	return response.New(200, p.method+" show "+mux.Vars(r)["photo"])
+}
+
+func (p *photoController) Edit(r *http.Request) responseconstract.Responser {
+	return response.New(200, p.method+" edit "+mux.Vars(r)["photo"])
+}
+
+func (p *photoController) Update(r *http.Request) responseconstract.Responser {
+	return response.New(200, p.method+" update "+mux.Vars(r)["photo"])
+}
+
+func (p *photoController) Destroy(r *http.Request) responseconstract.Responser {
+	return nil
+}
+
+type categoryController struct{}
+
+func (c categoryController) Index(r *http.Request) responseconstract.Responser {
+	return response.New(200, "categories")
+}
+
+type badController struct{}
+
+func (b *badController) Show(r *http.Request) string {
+	return ""
+}
+
+func TestRouter_Resource(t *testing.T) {
+	t.Run("routes", func(t *testing.T) {
+		r := New()
+		r.Resource("/photos", new(photoController))
+		for _, c := range []struct {
+			method, path string
+			code         int
+			body         string
+		}{
+			{"GET", "/photos", 200, "GET index "},
+			{"GET", "/photos/create", 200, "GET create"},
+			{"POST", "/photos", 201, "POST store"},
+			{"GET", "/photos/1", 200, "GET show 1"},
+			{"GET", "/photos/1/edit", 200, "GET edit 1"},
+			{"PUT", "/photos/1", 200, "PUT update 1"},
+			{"PATCH", "/photos/1", 200, "PATCH update 1"},
+			{"DELETE", "/photos/1", 200, ""},
+		} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(c.method, c.path, nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, c.code, w.Code, c.method+" "+c.path)
+			assert.Equal(t, c.body, w.Body.String(), c.method+" "+c.path)
+		}
+		url, err := r.Get("photos.edit").URL("photo", "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "/photos/1/edit", url.String())
+	})
+
+	t.Run("only and except", func(t *testing.T) {
+		r := New()
+		resource := r.Resource("/photos", new(photoController), OnlyActions("index", "show", "destroy"), ExceptActions("destroy"))
+		assert.NotNil(t, resource.Route("index"))
+		assert.NotNil(t, resource.Route("show"))
+		assert.Nil(t, resource.Route("destroy"))
+		assert.Nil(t, resource.Route("store"))
+		assert.Nil(t, r.Get("photos.store"))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		r := New()
+		r.Resource("/users/{user}/photos", new(photoController))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users/2/photos", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, "GET index 2", w.Body.String())
+
+		url, err := r.Get("users.photos.show").URL("user", "2", "photo", "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "/users/2/photos/1", url.String())
+	})
+
+	t.Run("shallow", func(t *testing.T) {
+		r := New()
+		r.Resource("/users/{user}/photos", new(photoController), ShallowResource(), ResourceName("photos"))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/photos/1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, "GET show 1", w.Body.String())
+
+		url, err := r.Get("photos.index").URL("user", "2")
+		assert.NoError(t, err)
+		assert.Equal(t, "/users/2/photos", url.String())
+	})
+
+	t.Run("shallow with static prefix", func(t *testing.T) {
+		r := New()
+		r.Resource("/admin/users/{user}/albums/{album}/photos", new(photoController), ShallowResource())
+		assert.Equal(t, "GET show 1", serve(r, "GET", "/admin/photos/1").Body.String())
+
+		template, err := r.Get("admin.users.albums.photos.show").GetPathTemplate()
+		assert.NoError(t, err)
+		assert.Equal(t, "/admin/photos/{photo}", template)
+		template, err = r.Get("admin.users.albums.photos.index").GetPathTemplate()
+		assert.NoError(t, err)
+		assert.Equal(t, "/admin/users/{user}/albums/{album}/photos", template)
+	})
+
+	t.Run("nested shallow member", func(t *testing.T) {
+		r := New()
+		r.Resource("/users/{user}/albums/{album}/photos", new(photoController), ShallowResource(), ResourceName("photos"))
+		assert.Equal(t, "GET show 1", serve(r, "GET", "/photos/1").Body.String())
+		assert.Equal(t, 404, serve(r, "GET", "/users/2/albums/3/photos/1").Code)
+
+		url, err := r.Get("photos.show").URL("photo", "1")
+		assert.NoError(t, err)
+		assert.Equal(t, "/photos/1", url.String())
+		url, err = r.Get("photos.index").URL("user", "2", "album", "3")
+		assert.NoError(t, err)
+		assert.Equal(t, "/users/2/albums/3/photos", url.String())
+	})
+
+	t.Run("static controller", func(t *testing.T) {
+		r := New()
+		r.Resource("/categories", categoryController{}, ResourceParameter("id")).Use(new(staticMiddleware))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/categories?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "categories", w.Body.String())
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		r := New()
+		assert.Panics(t, func() {
+			r.Resource("/photos", new(badController))
+		})
+		assert.Panics(t, func() {
+			r.Resource("/categories", categoryController{}, OnlyActions("show"))
+		})
+		assert.Panics(t, func() {
+			r.Resource("/photos/{photo}", new(photoController))
+		})
+	})
+}
+
+func TestSingular(t *testing.T) {
+	for plural, expected := range map[string]string{
+		"photos":     "photo",
+		"categories": "category",
+		"boxes":      "box",
+		"addresses":  "address",
+		"staff":      "staff",
+	} {
+		assert.Equal(t, expected, singular(plural))
+	}
+}
+
+func TestShallowPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/users/{user}/photos":                              "/photos",
+		"/admin/users/{user}/albums/{album}/photos":         "/admin/photos",
+		"/admin/users/{user}/archive/albums/{album}/photos": "/admin/archive/photos",
+		"/admin/regions/{region}/{zone}/photos":             "/admin/photos",
+		"/{tenant}/photos":                                  "/photos",
+	} {
+		assert.Equal(t, expected, shallowPath(path), path)
+	}
+}
@@ -1,22 +1,49 @@
 package mux
 
 import (
+	"context"
 	"net/http"
 	"reflect"
+	"regexp"
+	"sync/atomic"
+	"time"
 
 	pipelinecontract "github.com/gopi-frame/contract/pipeline"
-	"github.com/gopi-frame/contract/response"
+	responseconstract "github.com/gopi-frame/contract/response"
 	"github.com/gopi-frame/contract/router"
 	"github.com/gopi-frame/pipeline"
+	"github.com/gopi-frame/response"
 	"github.com/gorilla/mux"
 )
 
 type Route struct {
 	*mux.Route
 
-	originalHandler                 router.Handler
-	middlewareConstructorIndexCache map[reflect.Type]int
-	middlewares                     []router.Middleware
+	router              *Router
+	originalHandler     router.Handler
+	middlewares         []router.Middleware
+	excludedMiddlewares []any
+	raw                 bool
+	// controller and action are the controller type and method handling the route, if known, see [RouteFromContext].
+	controller reflect.Type
+	action     string
+	// autoHead reports whether the route has been registered for HEAD as a GET route, see [Route.WithoutAutoHead].
+	autoHead   bool
+	noAutoHead bool
+	// pathPolicy is the path policy of the route's router when it was registered, see [PathPolicy].
+	pathPolicy PathPolicy
+	// caseInsensitivePath matches the path of the route regardless of case, if its path policy is case-insensitive,
+	// and hostVars is the number of variables in its host.
+	caseInsensitivePath *regexp.Regexp
+	hostVars            int
+	chain               atomic.Pointer[middlewareChain]
+}
+
+func newRoute(r *Router, handler router.Handler) *Route {
+	return &Route{
+		router:          r,
+		originalHandler: handler,
+	}
 }
 
 func (r *Route) Name(name string) router.Route {
@@ -24,43 +51,165 @@ func (r *Route) Name(name string) router.Route {
 	return r
 }
 
+// Use appends middlewares to the route.
+// They run after the middlewares of the router and groups the route belongs to.
 func (r *Route) Use(middlewares ...router.Middleware) router.Route {
 	if len(middlewares) == 0 {
 		return r
 	}
 	r.middlewares = append(r.middlewares, middlewares...)
-	r.Route.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		var p = pipeline.New[*http.Request, response.Responser]().Send(req)
-		var pipes []pipelinecontract.Pipe[*http.Request, response.Responser]
-		for _, middleware := range r.middlewares {
-			if cm, ok := middleware.(router.ConstructableMiddleware); ok {
-				cmType := reflect.Indirect(reflect.ValueOf(cm)).Type()
-				middleware := reflect.New(cmType)
-				var constructor reflect.Value
-				if constructorIndex, ok := r.middlewareConstructorIndexCache[cmType]; !ok {
-					method, _ := cmType.MethodByName("Construct")
-					if r.middlewareConstructorIndexCache == nil {
-						r.middlewareConstructorIndexCache = make(map[reflect.Type]int)
-					}
-					r.middlewareConstructorIndexCache[cmType] = method.Index
-					constructor = middleware.Method(method.Index)
-				} else {
-					constructor = middleware.Method(constructorIndex)
-				}
-				constructor.Call([]reflect.Value{reflect.ValueOf(req)})
-				pipes = append(pipes, middleware.Interface().(pipelinecontract.Pipe[*http.Request, response.Responser]))
-			} else {
-				pipes = append(pipes, middleware)
-			}
+	r.router.middlewareConstructorIndexCache.warm(middlewares...)
+	r.router.middlewareRegistry.changed()
+	return r
+}
+
+// WithoutMiddleware excludes middlewares from the route,
+// including the ones inherited from its router and groups.
+// See [Router.WithoutMiddleware] for how they are matched.
+func (r *Route) WithoutMiddleware(middlewares ...any) *Route {
+	r.excludedMiddlewares = append(r.excludedMiddlewares, middlewares...)
+	r.router.middlewareRegistry.changed()
+	return r
+}
+
+// Raw makes the route bypass every middleware, including the ones of its router and groups,
+// e.g. for a mounted [http.Handler] or static files which handle everything themselves.
+func (r *Route) Raw() *Route {
+	r.raw = true
+	r.router.middlewareRegistry.changed()
+	return r
+}
+
+// middlewareChain is the middleware chain of a route, resolved for a version of the middleware configuration.
+type middlewareChain struct {
+	version     uint64
+	middlewares []router.Middleware
+	cors        *CORSMiddleware
+	err         error
+}
+
+// resolveChain returns the middleware chain of the route:
+// the middlewares of its router and the router's parents, followed by its own,
+// with named middlewares resolved, excluded middlewares removed and priorities applied.
+// The chain is resolved once, then again only after the middleware configuration changes.
+func (r *Route) resolveChain() *middlewareChain {
+	registry := r.router.middlewareRegistry
+	version := registry.version.Load()
+	if chain := r.chain.Load(); chain != nil && chain.version == version {
+		return chain
+	}
+	chain := &middlewareChain{version: version}
+	if !r.raw {
+		middlewares, excluded := r.router.inheritedMiddlewares()
+		middlewares = append(middlewares, r.middlewares...)
+		middlewares, chain.err = registry.resolve(middlewares)
+		middlewares = withoutMiddlewares(middlewares, excluded)
+		middlewares = withoutMiddlewares(middlewares, r.excludedMiddlewares)
+		chain.middlewares = registry.sort(middlewares)
+		chain.cors = innermostCORSMiddleware(chain.middlewares)
+		if chain.err == nil {
+			chain.err = corsError(chain.middlewares)
 		}
-		resp := p.Through(pipes...).Then(func(request *http.Request) response.Responser {
-			req = request
-			if r.originalHandler != nil {
-				return r.originalHandler(request)
+	}
+	r.chain.Store(chain)
+	return chain
+}
+
+// dispatch is the state of a request handled by a route.
+type dispatch struct {
+	route   *Route
+	started time.Time
+	writer  *responseWriter
+	// pipes are the pipes of middlewares which run inside the handler, e.g. controller middlewares,
+	// to be terminated along with the pipes of the route.
+	pipes []pipelinecontract.Pipe[*http.Request, responseconstract.Responser]
+	// finished is when the response has been written, before the request is terminated.
+	finished time.Time
+	// inFlight are the metrics which count the request in flight, until the response has been written,
+	// and landed reports whether they have stopped counting it.
+	inFlight []inFlightRequest
+	landed   bool
+	// models are the models resolved from the path parameters, see [Bind].
+	models map[string]any
+}
+
+type dispatchKey struct{}
+
+// dispatchFromContext returns the state of the request handled by a route, or nil if there is none.
+func dispatchFromContext(ctx context.Context) *dispatch {
+	d, _ := ctx.Value(dispatchKey{}).(*dispatch)
+	return d
+}
+
+func (r *Route) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	w := &responseWriter{ResponseWriter: rw}
+	// the handler writes to hw, which discards the body of responses to HEAD requests
+	var hw *headWriter
+	var out http.ResponseWriter = w
+	if req.Method == http.MethodHead && r.autoHead {
+		hw = &headWriter{ResponseWriter: w}
+		out = hw
+	}
+	d := &dispatch{route: r, started: time.Now(), writer: w}
+	// the request is not terminated if the handler panics, but it is no longer in flight
+	defer d.landInFlight()
+	req = req.WithContext(context.WithValue(req.Context(), dispatchKey{}, d))
+	span := r.startSpan(req)
+	if span != nil {
+		req = req.WithContext(context.WithValue(req.Context(), spanKey{}, span))
+	}
+	var scope *Scope
+	if container := r.router.root().container; container != nil {
+		scope = container.NewScope(req)
+		// the scope is closed once the request is terminated, or if the handler panics
+		defer func() {
+			if scope != nil {
+				_ = scope.Close()
 			}
-			return nil
-		})
-		resp.ServeHTTP(w, req)
+		}()
+		req = req.WithContext(scope.WithContext(req.Context()))
+		scope.request = req
+	}
+	var pipes []pipelinecontract.Pipe[*http.Request, responseconstract.Responser]
+	if chain := r.resolveChain(); chain.err != nil {
+		// the middlewares of the route can't be resolved, which is reported by [Router.Validate]
+		pipes = append(pipes, &abortedPipe{response: errorResponse(req, chain.err)})
+	} else {
+		pipes = r.router.middlewareConstructorIndexCache.pipes(req, chain.middlewares)
+	}
+	through := pipes
+	if span != nil {
+		through = tracePipes(pipes)
+	}
+	resp := pipeline.New[*http.Request, responseconstract.Responser]().Send(req).Through(through...).Then(func(request *http.Request) responseconstract.Responser {
+		req = request
+		if resp, aborted := r.resolveModels(request, d); aborted {
+			return resp
+		}
+		if span == nil {
+			return r.originalHandler(request)
+		}
+		ctx, handlerSpan := StartSpan(request.Context(), "handler")
+		defer handlerSpan.End()
+		return r.originalHandler(request.WithContext(ctx))
+	})
+	if resp == nil {
+		resp = response.New(http.StatusOK)
+	}
+	resp.ServeHTTP(out, req)
+	if hw != nil {
+		hw.finish()
+	}
+	if span != nil {
+		d.endSpan(span)
+	}
+	d.finished = time.Now()
+	d.landInFlight()
+	terminating := scope
+	scope = nil
+	terminate(append(pipes, d.pipes...), req, resp, func() {
+		if terminating != nil {
+			_ = terminating.Close()
+		}
 	})
-	return r
 }
@@ -0,0 +1,54 @@
+package mux
+
+import (
+	"context"
+	"reflect"
+)
+
+// RouteInfo describes the route handling a request.
+type RouteInfo struct {
+	// Name is the name of the route, if any.
+	Name string
+	// Template is the path template of the route, e.g. /users/{id}.
+	Template string
+	// GroupPrefix is the path prefix of the groups the route belongs to, e.g. /api/users.
+	GroupPrefix string
+	// Controller is the type of the controller of the route, if any.
+	Controller reflect.Type
+	// Action is the name of the controller method handling the route, e.g. Show,
+	// for routes registered with [RouteAction], resources and declared routes.
+	// It is empty for routes registered with a handler, including one returned by [Action],
+	// since the method can't be recovered from the handler.
+	Action string
+}
+
+// RouteFromContext returns the route handling the request of the context.
+// It reports false if the request is not handled by a route of a [Router].
+func RouteFromContext(ctx context.Context) (RouteInfo, bool) {
+	d := dispatchFromContext(ctx)
+	if d == nil {
+		return RouteInfo{}, false
+	}
+	template, _ := d.route.GetPathTemplate()
+	info := RouteInfo{
+		Name:        d.route.GetName(),
+		Template:    template,
+		GroupPrefix: d.route.router.prefix,
+		Controller:  d.route.controller,
+		Action:      d.route.action,
+	}
+	if info.Controller == nil {
+		info.Controller = d.route.router.controller()
+	}
+	return info, true
+}
+
+// controller returns the type of the controller of the router or the nearest of its parents, if any.
+func (r *Router) controller() reflect.Type {
+	for ; r != nil; r = r.parent {
+		if r.ccType != nil {
+			return r.ccType
+		}
+	}
+	return nil
+}
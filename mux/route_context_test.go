@@ -0,0 +1,71 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+// routeInfoMiddleware records the route of the request before the handler runs.
+type routeInfoMiddleware struct {
+	info RouteInfo
+}
+
+func (m *routeInfoMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	m.info, _ = RouteFromContext(r.Context())
+	return next(r)
+}
+
+func TestRouteFromContext(t *testing.T) {
+	middleware := new(routeInfoMiddleware)
+	r := New()
+	r.AliasMiddleware("auth", new(staticMiddleware))
+	r.Use(middleware)
+	r.GET("/", func(request *http.Request) responseconstract.Responser {
+		return response.New(200)
+	}).Name("home")
+	r.Group(&RouteGroup{Prefix: "/api"}, func(router routercontract.Router) {
+		router.Controller(new(actionController), func(router routercontract.Router) {
+			RouteAction(router, []string{http.MethodGet}, "/show/{id}", (*actionController).Show)
+			router.GET("/form", Action((*actionController).Form))
+			router.Group(&RouteGroup{Prefix: "/nested"}, func(router routercontract.Router) {
+				router.GET("/show", func(request *http.Request) responseconstract.Responser {
+					return response.New(200)
+				})
+			})
+		})
+		router.(*Router).Resource("/photos", new(photoController), OnlyActions("show"))
+	})
+	controller := new(actionController)
+	r.Controller(controller, func(router routercontract.Router) {
+		router.POST("/form", controller.Form)
+	})
+	r.Controller(new(declarativeController), nil)
+
+	actionControllerType := reflect.TypeFor[*actionController]()
+	for _, c := range []struct {
+		method, path string
+		info         RouteInfo
+	}{
+		{"GET", "/", RouteInfo{Name: "home", Template: "/"}},
+		{"GET", "/api/actions/show/1", RouteInfo{Template: "/api/actions/show/{id}", GroupPrefix: "/api/actions", Controller: actionControllerType, Action: "Show"}},
+		{"GET", "/api/actions/form", RouteInfo{Template: "/api/actions/form", GroupPrefix: "/api/actions", Controller: actionControllerType}},
+		{"GET", "/api/actions/nested/show", RouteInfo{Template: "/api/actions/nested/show", GroupPrefix: "/api/actions/nested", Controller: actionControllerType}},
+		{"GET", "/api/photos/1", RouteInfo{Name: "photos.show", Template: "/api/photos/{photo}", GroupPrefix: "/api", Controller: reflect.TypeFor[*photoController](), Action: "Show"}},
+		{"POST", "/actions/form", RouteInfo{Template: "/actions/form", GroupPrefix: "/actions", Controller: actionControllerType, Action: "Form"}},
+		{"POST", "/posts/1/publish", RouteInfo{Template: "/posts/{id}/publish", GroupPrefix: "/posts", Controller: reflect.TypeFor[*declarativeController](), Action: "Store"}},
+	} {
+		middleware.info = RouteInfo{}
+		serve(r, c.method, c.path)
+		assert.Equal(t, c.info, middleware.info, c.path)
+	}
+
+	_, ok := RouteFromContext(context.Background())
+	assert.False(t, ok)
+}
@@ -10,6 +10,11 @@ import (
 type RouteGroup struct {
 	Prefix string
 	Host   string
+	// WithoutMiddleware excludes middlewares inherited from the parent router from every route in the group,
+	// see [Router.WithoutMiddleware] for how they are matched.
+	WithoutMiddleware []any
+	// PathPolicy is the path policy of the routes in the group, or nil to inherit the one of the parent router.
+	PathPolicy *PathPolicy
 
 	p *Router
 }
@@ -28,12 +33,24 @@ func (r *RouteGroup) Build() router.Router {
 			route = r.p.Host(r.Host)
 		}
 	}
-	if route != nil {
-		return &Router{
-			Router: route.Subrouter(),
+	if route == nil {
+		// a group without prefix and host still gets its own sub router,
+		// so that its middlewares don't leak into the parent router
+		route = r.p.NewRoute()
+	}
+	sub := &Router{
+		Router: route.Subrouter(),
 
-			middlewareConstructorIndexCache: r.p.middlewareConstructorIndexCache,
-		}
+		parent:                          r.p,
+		prefix:                          r.p.prefix + r.Prefix,
+		excludedMiddlewares:             append([]any(nil), r.WithoutMiddleware...),
+		middlewareRegistry:              r.p.middlewareRegistry,
+		middlewareConstructorIndexCache: r.p.middlewareConstructorIndexCache,
+		encodedPath:                     r.p.encodedPath,
+	}
+	if r.PathPolicy != nil {
+		policy := *r.PathPolicy
+		sub.setPathPolicy(&policy)
 	}
-	return r.p
+	return sub
 }
@@ -2,10 +2,13 @@ package mux
 
 import (
 	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
 	"github.com/gopi-frame/response"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -87,3 +90,171 @@ func TestRoute_Use(t *testing.T) {
 		})
 	})
 }
+
+func TestRoute_WithoutMiddleware(t *testing.T) {
+	t.Run("router middleware", func(t *testing.T) {
+		r := New()
+		r.Use(new(staticMiddleware))
+		r.GET("/login", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, "login")
+		}).(*Route).WithoutMiddleware(new(staticMiddleware))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "login", w.Body.String())
+	})
+
+	t.Run("route middleware", func(t *testing.T) {
+		r := New()
+		r.GET("/login", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, "login")
+		}).Use(new(staticMiddleware), new(nonStaticMiddleware)).(*Route).WithoutMiddleware("nonStaticMiddleware")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/login?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+		assert.Equal(t, "", w.Header().Get("Request-Method"))
+	})
+}
+
+type terminableMiddleware struct {
+	method string
+}
+
+func (t *terminableMiddleware) Construct(r *http.Request) {
+	t.method = r.Method
+}
+
+func (t *terminableMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	return next(r)
+}
+
+func (t *terminableMiddleware) Terminate(r *http.Request, _ responseconstract.Responser) {
+	terminatedRequests <- t.method + " " + r.URL.Path
+}
+
+var terminatedRequests = make(chan string, 1)
+
+type staticTerminableMiddleware struct {
+	terminated []int
+}
+
+func (s *staticTerminableMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	return next(r)
+}
+
+func (s *staticTerminableMiddleware) Terminate(_ *http.Request, resp responseconstract.Responser) {
+	w := httptest.NewRecorder()
+	resp.ServeHTTP(w, nil)
+	s.terminated = append(s.terminated, w.Code)
+}
+
+func TestTerminableMiddleware(t *testing.T) {
+	t.Run("router", func(t *testing.T) {
+		r := New()
+		m := new(staticTerminableMiddleware)
+		r.Use(m)
+		r.GET("/get", func(request *http.Request) responseconstract.Responser {
+			return response.New(201)
+		})
+		r.GET("/nil", func(request *http.Request) responseconstract.Responser {
+			return nil
+		})
+		for _, path := range []string{"/get", "/nil"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", path, nil)
+			r.ServeHTTP(w, req)
+			terminations.Wait()
+		}
+		assert.Equal(t, []int{201, 200}, m.terminated)
+	})
+
+	t.Run("constructable route middleware", func(t *testing.T) {
+		r := New()
+		r.POST("/post", func(request *http.Request) responseconstract.Responser {
+			return response.New(200)
+		}).Use(new(terminableMiddleware))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/post", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "POST /post", <-terminatedRequests)
+	})
+}
+
+func TestRoute_Use_chain(t *testing.T) {
+	handler := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, strings.Join(request.Header.Values("Order"), ","))
+	}
+
+	r := New()
+	r.Use(new(sessionMiddleware))
+	r.Group(&RouteGroup{Prefix: "/group"}, func(router routercontract.Router) {
+		router.Use(new(authMiddleware))
+		router.GET("/get", handler).Use(new(logMiddleware))
+		router.GET("/nil", func(request *http.Request) responseconstract.Responser {
+			return nil
+		}).Use(new(logMiddleware))
+		router.Handle([]string{"GET"}, "/handle", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(strings.Join(req.Header.Values("Order"), ",")))
+		})).Use(new(logMiddleware))
+	})
+
+	for path, expected := range map[string]string{
+		"/group/get":    "session,auth,log",
+		"/group/nil":    "",
+		"/group/handle": "session,auth,log",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code, path)
+		assert.Equal(t, expected, w.Body.String(), path)
+	}
+}
+
+type slowTerminableMiddleware struct {
+	release    chan struct{}
+	terminated chan struct{}
+}
+
+func (s *slowTerminableMiddleware) Handle(r *http.Request, next routercontract.Handler) responseconstract.Responser {
+	return next(r)
+}
+
+func (s *slowTerminableMiddleware) Terminate(r *http.Request, _ responseconstract.Responser) {
+	<-s.release
+	close(s.terminated)
+}
+
+func TestTerminableMiddleware_afterResponse(t *testing.T) {
+	m := &slowTerminableMiddleware{release: make(chan struct{}), terminated: make(chan struct{})}
+	r := New()
+	r.Use(m)
+	r.GET("/get", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/get")
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	assert.Equal(t, int64(5), resp.ContentLength)
+	select {
+	case <-m.terminated:
+		assert.Fail(t, "the response is sent before the request is terminated")
+	default:
+	}
+	close(m.release)
+	<-m.terminated
+}
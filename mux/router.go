@@ -1,13 +1,16 @@
 package mux
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 
-	pipelinecontract "github.com/gopi-frame/contract/pipeline"
-	"github.com/gopi-frame/pipeline"
 	"github.com/gopi-frame/response"
 
 	responseconstract "github.com/gopi-frame/contract/response"
@@ -19,49 +22,177 @@ import (
 type Router struct {
 	*mux.Router
 
+	parent                          *Router
+	prefix                          string
 	middlewares                     []routercontract.Middleware
-	middlewareConstructorIndexCache map[reflect.Type]int
-	ccType                          reflect.Type
-	controllerMethodIndexCache      map[string]int
+	excludedMiddlewares             []any
+	middlewareRegistry              *middlewareRegistry
+	middlewareConstructorIndexCache *constructorIndexCache
+	container                       *Container
+	spanExporter                    SpanExporter
+	bindings                        map[string]binding
+	bindingsMu                      sync.RWMutex
+	errorHandler                    func(request *http.Request, err error) responseconstract.Responser
+	// ccType is the type of the controller of the router, if any, see [Router.Controller],
+	// and controllerMethodIndexCache holds the index of its Construct method if it is constructable.
+	ccType                     reflect.Type
+	controllerMethodIndexCache map[string]int
+	// routes are the routes of the router and all its groups, on the root router.
+	routes map[*mux.Route]*Route
+	// headRoutes are the routes registered for HEAD, which take precedence over GET routes, on the root router.
+	headRoutes []*Route
+	// methods are the distinct methods of the routes, on the root router.
+	methods []string
+	// caseInsensitiveRoutes are the routes matched regardless of case, see [PathPolicy.CaseInsensitive],
+	// and normalizeEncoding reports whether any route normalizes percent-encodings, on the root router.
+	caseInsensitiveRoutes []*Route
+	normalizeEncoding     bool
+	noAutoHead            bool
+	// pathPolicy is the path policy of the router, or nil to inherit the one of its parent, see [PathPolicy].
+	pathPolicy  *PathPolicy
+	encodedPath bool
 }
 
-func New() *Router {
-	return &Router{
+func New(options ...RouterOption) *Router {
+	r := &Router{
 		Router:                          mux.NewRouter(),
-		middlewareConstructorIndexCache: make(map[reflect.Type]int),
+		middlewareRegistry:              newMiddlewareRegistry(),
+		middlewareConstructorIndexCache: newConstructorIndexCache(),
 		controllerMethodIndexCache:      make(map[string]int),
 	}
+	// paths are cleaned before matching as the path policies allow, see [Router.ServeHTTP]
+	r.Router.SkipClean(true)
+	r.Router.NotFoundHandler = r.notFoundHandler(nil)
+	r.Router.MethodNotAllowedHandler = r.methodNotAllowedHandler(nil)
+	for _, option := range options {
+		option(r)
+	}
+	return r
 }
 
 func (r *Router) Use(middlewares ...routercontract.Middleware) routercontract.Router {
 	if len(middlewares) != 0 {
 		r.middlewares = append(r.middlewares, middlewares...)
+		r.middlewareConstructorIndexCache.warm(middlewares...)
+		r.middlewareRegistry.changed()
+	}
+	return r
+}
+
+// SetContainer sets the container which injects services into constructable controllers and middlewares
+// of the router and all its groups. It should be set on the root router.
+func (r *Router) SetContainer(container *Container) *Router {
+	r.container = container
+	r.middlewareRegistry.changed()
+	return r
+}
+
+// addRoute registers the route on the root router, to find it from the routes matched by gorilla.
+func (r *Router) addRoute(route *Route) {
+	root := r.root()
+	if root.routes == nil {
+		root.routes = make(map[*mux.Route]*Route)
+	}
+	root.routes[route.Route] = route
+	route.pathPolicy = r.effectivePathPolicy()
+	if route.pathPolicy.NormalizeEncoding {
+		root.normalizeEncoding = true
+	}
+	r.middlewareRegistry.changed()
+}
+
+// root returns the router at the top of the group tree.
+func (r *Router) root() *Router {
+	for r.parent != nil {
+		r = r.parent
 	}
 	return r
 }
 
+// AliasMiddleware registers the middleware under the name,
+// so that it can be referenced with [Named] by the router and all its groups.
+func (r *Router) AliasMiddleware(name string, middleware routercontract.Middleware) *Router {
+	r.middlewareRegistry.alias(name, middleware)
+	r.middlewareConstructorIndexCache.warm(middleware)
+	return r
+}
+
+// MiddlewareGroup registers the middlewares as a group under the name,
+// so that they can be referenced together with [Named] by the router and all its groups.
+// A group can contain named middlewares, including other groups.
+func (r *Router) MiddlewareGroup(name string, middlewares ...routercontract.Middleware) *Router {
+	r.middlewareRegistry.group(name, middlewares)
+	r.middlewareConstructorIndexCache.warm(middlewares...)
+	return r
+}
+
+// MiddlewarePriority sets the order in which known middlewares always run,
+// regardless of whether they are attached to the router, a group or a route.
+// Targets are matched like in [Router.WithoutMiddleware].
+// Middlewares which match no target keep their positions in the chain.
+// The priority list is shared by the router and all its groups.
+func (r *Router) MiddlewarePriority(middlewares ...any) *Router {
+	r.middlewareRegistry.prioritize(middlewares)
+	return r
+}
+
+// WithoutMiddleware excludes middlewares from every route of the router.
+// Each target can be a middleware instance or a [reflect.Type], which are matched by type,
+// or a string, which is matched against the alias of the middleware,
+// or its type name with or without the package qualifier.
+// It is meant to be used in groups, to opt out of middlewares inherited from the parent router.
+func (r *Router) WithoutMiddleware(middlewares ...any) *Router {
+	r.excludedMiddlewares = append(r.excludedMiddlewares, middlewares...)
+	r.middlewareRegistry.changed()
+	return r
+}
+
+// inheritedMiddlewares returns the middlewares and excluded middlewares of the router and its parents, from the root down.
+// They are collected when the chains of the routes are resolved,
+// so that middlewares added to a parent after a group has been created still apply to the group.
+func (r *Router) inheritedMiddlewares() ([]routercontract.Middleware, []any) {
+	var middlewares []routercontract.Middleware
+	var excluded []any
+	if r.parent != nil {
+		middlewares, excluded = r.parent.inheritedMiddlewares()
+	}
+	return append(middlewares, r.middlewares...), append(excluded, r.excludedMiddlewares...)
+}
+
 func (r *Router) Group(group routercontract.RouteGroup, builder func(routercontract.Router)) routercontract.Router {
 	g := group.(*RouteGroup)
 	g.p = r
 	sub := g.Build()
-	sub.(*Router).middlewares = append(sub.(*Router).middlewares, g.p.middlewares...)
 	builder(sub)
 	return sub
 }
 
+// Controller registers the routes of the controller in its route group.
+// Routes declared by the controller, with a Routes method or [Endpoint] fields, are registered first,
+// and it panics at registration if any of them is invalid.
+// The builder, which can be nil, can register more routes.
+//
+// The actions of a constructable controller should be bound with [Action] or [RouteAction].
+// Binding a method value, e.g. controller.Show, is deprecated: it is recognized by the name of its function,
+// which isn't reliable, and a warning is logged when it is first bound.
 func (r *Router) Controller(controller routercontract.Controller, builder func(routercontract.Router)) routercontract.Router {
 	return r.Group(controller.RouteGroup(), func(r routercontract.Router) {
-		if _, ok := controller.(routercontract.ConstructableController); ok {
+		r.(*Router).ccType = reflect.TypeOf(controller)
+		if _, ok := constructMethod(reflect.TypeOf(controller)); ok {
 			r := r.(*Router)
 			r.controllerMethodIndexCache = make(map[string]int)
-			r.ccType = reflect.TypeOf(controller)
 			if r.ccType.Kind() != reflect.Pointer {
 				panic(exception.NewArgumentException("controller", controller, "controller should be a pointer"))
 			}
 			method, _ := r.ccType.MethodByName("Construct")
 			r.controllerMethodIndexCache["Construct"] = method.Index
 		}
-		builder(r)
+		if err := registerDeclaredRoutes(r.(*Router), controller); err != nil {
+			panic(err)
+		}
+		if builder != nil {
+			builder(r)
+		}
 	})
 }
 
@@ -94,85 +225,165 @@ func (r *Router) HEAD(path string, handler routercontract.Handler) routercontrac
 }
 
 func (r *Router) Route(methods []string, path string, handler routercontract.Handler) routercontract.Route {
-	if r.ccType != nil {
-		fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
-		ss := strings.Split(fn, ".")
-		fn = ss[len(ss)-1]
-		isMethod := strings.HasSuffix(fn, "-fm")
-		fn = strings.TrimRight(fn, "-fm")
-		method, ok := r.ccType.MethodByName(fn)
-		if ok && isMethod {
-			r.controllerMethodIndexCache[fn] = method.Index
-			handler = func(request *http.Request) responseconstract.Responser {
-				var cc = reflect.New(r.ccType.Elem())
-				cc.Method(r.controllerMethodIndexCache["Construct"]).Call([]reflect.Value{reflect.ValueOf(request)})
-				out := cc.Method(r.controllerMethodIndexCache[fn]).Call([]reflect.Value{reflect.ValueOf(request)})
-				return out[0].Interface().(responseconstract.Responser)
-			}
+	var controller reflect.Type
+	var action string
+	if _, constructable := r.controllerMethodIndexCache["Construct"]; constructable {
+		if bound, name, ok := r.bindMethodValue(handler); ok {
+			handler, controller, action = bound, r.ccType, name
 		}
 	}
+	route := newRoute(r, handler)
+	route.controller, route.action = controller, action
+	r.register(route, methods, path)
+	return route
+}
 
-	route := r.Router.Methods(methods...).Path(path).HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		resp := func(request *http.Request) responseconstract.Responser {
-			p := pipeline.New[*http.Request, responseconstract.Responser]().Send(request)
-			pipes := make([]pipelinecontract.Pipe[*http.Request, responseconstract.Responser], 0)
-			for _, middleware := range r.middlewares {
-				if cm, ok := middleware.(routercontract.ConstructableMiddleware); ok {
-					cmType := reflect.Indirect(reflect.ValueOf(cm)).Type()
-					middleware := reflect.New(cmType)
-					var constructor reflect.Value
-					if constructorIndex, ok := r.middlewareConstructorIndexCache[cmType]; !ok {
-						method, _ := cmType.MethodByName("Construct")
-						r.middlewareConstructorIndexCache[cmType] = method.Index
-						constructor = middleware.Method(method.Index)
-					} else {
-						constructor = middleware.Method(constructorIndex)
-					}
-					constructor.Call([]reflect.Value{
-						reflect.ValueOf(request),
-					})
-					pipes = append(pipes, middleware.Interface().(routercontract.ConstructableMiddleware))
-				} else {
-					pipes = append(pipes, middleware)
-				}
-			}
-			return p.Through(pipes...).Then(func(request *http.Request) responseconstract.Responser {
-				return handler(request)
-			})
-		}(req)
-		if resp == nil {
-			resp = response.New(http.StatusOK)
-		}
-		resp.ServeHTTP(w, req)
-	})
-	return &Route{
-		Route:           route,
-		originalHandler: handler,
+// methodValueWarning logs once that binding method values is deprecated, see [Router.bindMethodValue].
+var methodValueWarning sync.Once
+
+// bindMethodValue binds a method value of the constructable controller of the router, e.g. controller.Show,
+// to a handler which calls the method on a new instance of the controller for every request.
+// It reports false if the handler is not a method value of the controller.
+// Method values are recognized by the name of their function, which isn't reliable under inlining or with closures,
+// so binding them is deprecated in favor of [Action] and [RouteAction], with a warning logged once.
+func (r *Router) bindMethodValue(handler routercontract.Handler) (routercontract.Handler, string, bool) {
+	fn := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	ss := strings.Split(fn, ".")
+	fn = ss[len(ss)-1]
+	isMethod := strings.HasSuffix(fn, "-fm")
+	fn = strings.TrimSuffix(fn, "-fm")
+	method, ok := r.ccType.MethodByName(fn)
+	if !ok || !isMethod {
+		return nil, "", false
 	}
+	methodValueWarning.Do(func() {
+		slog.Warn("mux: binding method values of constructable controllers is deprecated, use mux.Action or mux.RouteAction instead",
+			"controller", r.ccType.String(), "method", fn)
+	})
+	r.controllerMethodIndexCache[fn] = method.Index
+	// resolve everything the handler needs at registration time,
+	// so that concurrent requests never touch the router's state
+	ccType := r.ccType
+	constructIndex := r.controllerMethodIndexCache["Construct"]
+	methodIndex := method.Index
+	return func(request *http.Request) responseconstract.Responser {
+		var cc = reflect.New(ccType.Elem())
+		if err := injectScope(request, cc); err != nil {
+			return errorResponse(request, err)
+		}
+		if resp, aborted := callConstruct(request, cc.Method(constructIndex), reflect.ValueOf(request)); aborted {
+			return resp
+		}
+		return invokeAction(cc.Interface(), fn, request, func(request *http.Request) responseconstract.Responser {
+			out := cc.Method(methodIndex).Call([]reflect.Value{reflect.ValueOf(request)})
+			return out[0].Interface().(responseconstract.Responser)
+		})
+	}, fn, true
 }
 
 func (r *Router) Handle(methods []string, path string, handler http.Handler) routercontract.Route {
-	route := r.Router.Methods(methods...).Path(path).Handler(handler)
-	return &Route{
-		Route: route,
-		originalHandler: func(request *http.Request) responseconstract.Responser {
-			return response.NewHandlerWrapper(handler)
-		},
+	route := newRoute(r, func(request *http.Request) responseconstract.Responser {
+		return response.NewHandlerWrapper(handler)
+	})
+	r.register(route, methods, path)
+	return route
+}
+
+// register registers the route for the methods and the path.
+// GET routes are registered for HEAD as well, see [Route.WithoutAutoHead].
+func (r *Router) register(route *Route, methods []string, path string) {
+	root := r.root()
+	methods = slices.Clone(methods)
+	for i, method := range methods {
+		methods[i] = strings.ToUpper(method)
+	}
+	route.autoHead = slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) && r.answersHead()
+	if route.autoHead {
+		methods = append(methods, http.MethodHead)
+	}
+	route.Route = r.Router.Methods(methods...).Path(path)
+	if route.autoHead {
+		route.Route.MatcherFunc(route.matchHead)
+	} else if slices.Contains(methods, http.MethodHead) {
+		root.headRoutes = append(root.headRoutes, route)
+	}
+	route.Route.HandlerFunc(route.serveHTTP)
+	for _, method := range methods {
+		if !slices.Contains(root.methods, method) {
+			root.methods = append(root.methods, method)
+		}
+	}
+	r.addRoute(route)
+	if route.pathPolicy.CaseInsensitive && route.compileCaseInsensitivePath() {
+		root.caseInsensitiveRoutes = append(root.caseInsensitiveRoutes, route)
 	}
 }
 
 func (r *Router) Static(prefix string, root http.FileSystem) routercontract.Route {
-	route := r.Router.PathPrefix(prefix).Handler(http.StripPrefix(prefix, http.FileServer(root)))
-	return &Route{
-		Route: route,
-		originalHandler: func(request *http.Request) responseconstract.Responser {
-			return response.NewHandlerWrapper(http.StripPrefix(prefix, http.FileServer(root)))
-		},
+	handler := http.StripPrefix(prefix, http.FileServer(root))
+	route := newRoute(r, func(request *http.Request) responseconstract.Responser {
+		return response.NewHandlerWrapper(handler)
+	})
+	route.Route = r.Router.PathPrefix(prefix).HandlerFunc(route.serveHTTP)
+	r.addRoute(route)
+	return route
+}
+
+// Metrics exposes the metrics on a raw GET route of the path, e.g. /metrics,
+// which is not recorded by the metrics itself.
+func (r *Router) Metrics(path string, metrics *Metrics) *Route {
+	return r.Handle([]string{http.MethodGet}, path, metrics).(*Route).Raw()
+}
+
+// OnError sets the handler of errors which abort requests, e.g. returned by Construct,
+// for the router and all its groups which have no handler of their own.
+// Without handler, or if the handler returns nil, errors produce a 500 Internal Server Error response,
+// or a 404 Not Found response for [ErrModelNotFound].
+func (r *Router) OnError(handler func(request *http.Request, err error) responseconstract.Responser) {
+	r.errorHandler = handler
+}
+
+// errorResponse returns the response of the nearest error handler for the error
+// from the router of the route handling the request.
+func errorResponse(request *http.Request, err error) responseconstract.Responser {
+	if d := dispatchFromContext(request.Context()); d != nil {
+		for r := d.route.router; r != nil; r = r.parent {
+			if r.errorHandler != nil {
+				if resp := r.errorHandler(request, err); resp != nil {
+					return resp
+				}
+				break
+			}
+		}
+	}
+	if errors.Is(err, ErrModelNotFound) {
+		return response.New(http.StatusNotFound)
 	}
+	return response.New(http.StatusInternalServerError)
 }
 
+// OnNotFound sets the handler of requests which match no route,
+// once their path has been matched as the path policies allow, see [PathPolicy].
 func (r *Router) OnNotFound(handler routercontract.Handler) {
-	r.Router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	r.Router.NotFoundHandler = r.notFoundHandler(handler)
+}
+
+// notFoundHandler matches the path of requests which match no route as the path policies allow,
+// serving or redirecting the requests which match a route then, see [Router.normalizePath].
+// Other requests are answered by the handler, or with 404 Not Found.
+func (r *Router) notFoundHandler(handler routercontract.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		root := r.root()
+		if normalized, redirected := root.normalizePath(w, req); redirected {
+			return
+		} else if normalized != nil {
+			root.ServeHTTP(w, normalized)
+			return
+		}
+		if handler == nil {
+			http.NotFound(w, req)
+			return
+		}
 		resp := handler(req)
 		if resp == nil {
 			resp = response.New(http.StatusNotFound)
@@ -181,12 +392,155 @@ func (r *Router) OnNotFound(handler routercontract.Handler) {
 	})
 }
 
+// OnMethodNotAllowed sets the handler of requests whose path matches routes of other methods only.
+// The Allow header of the response lists the methods of these routes.
 func (r *Router) OnMethodNotAllowed(handler routercontract.Handler) {
-	r.Router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		resp := handler(req)
+	r.Router.MethodNotAllowedHandler = r.methodNotAllowedHandler(handler)
+}
+
+func (r *Router) methodNotAllowedHandler(handler routercontract.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Allow", strings.Join(r.allowedMethods(req), ", "))
+		var resp responseconstract.Responser
+		if handler != nil {
+			resp = handler(req)
+		}
 		if resp == nil {
 			resp = response.New(http.StatusMethodNotAllowed)
 		}
 		resp.ServeHTTP(w, req)
 	})
 }
+
+// ServeHTTP dispatches the request to the matching route.
+//
+// CORS preflight requests are answered by the CORS policy of the route matching the requested method, see [CORS].
+// Other OPTIONS requests whose path matches no OPTIONS route but routes of other methods
+// are answered with 204 No Content and the Allow header listing these methods.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// like gorilla/mux, paths which aren't clean are cleaned before matching,
+	// unless the policy of the route matching the clean path keeps them
+	if _, dirty := r.cleanRequestPath(req); dirty {
+		normalized, redirected := r.normalizePath(w, req)
+		if redirected {
+			return
+		}
+		if normalized != nil {
+			req = normalized
+		}
+	}
+	if req.Method == http.MethodOptions {
+		if isPreflight(req) {
+			if route := r.match(req, req.Header.Get("Access-Control-Request-Method")); route != nil {
+				if cm := route.corsMiddleware(); cm != nil {
+					cm.preflight(req).ServeHTTP(w, req)
+					return
+				}
+			}
+		}
+		if r.match(req, http.MethodOptions) == nil {
+			if allowed := r.allowedMethods(req); len(allowed) != 0 {
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+	}
+	// GET routes which don't answer HEAD requests don't match them, so gorilla doesn't see the method mismatch
+	if req.Method == http.MethodHead && r.match(req, http.MethodHead) == nil && len(r.allowedMethods(req)) != 0 {
+		r.Router.MethodNotAllowedHandler.ServeHTTP(w, req)
+		return
+	}
+	r.Router.ServeHTTP(w, req)
+}
+
+// Validate resolves the middleware chains of every route of the router and all its groups,
+// along with the middlewares declared by their controllers, and reports middleware names which are not registered,
+// parameters passed to middlewares which don't accept them, middleware groups which reference themselves,
+// invalid fields tagged with `bind` and services injected into controllers and middlewares
+// which are not registered in the container of the router.
+// It should be called once every route is registered, e.g. at startup;
+// otherwise a route whose middlewares can't be resolved answers requests with the response of the error handler,
+// see [Router.OnError], while the other routes are served.
+// It is safe to call concurrently.
+func (r *Router) Validate() error {
+	root := r.root()
+	registry := root.middlewareRegistry
+	registry.validation.Lock()
+	defer registry.validation.Unlock()
+	var errs []error
+	reported := make(map[string]bool)
+	_ = root.Router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		rt, ok := root.routes[route]
+		if !ok {
+			return nil
+		}
+		chain := rt.resolveChain()
+		err := chain.err
+		if err == nil {
+			err = rt.checkControllerMiddlewares()
+		}
+		if err == nil {
+			err = rt.checkFields(root.container, chain.middlewares)
+		}
+		if err != nil && !reported[err.Error()] {
+			reported[err.Error()] = true
+			template, _ := route.GetPathTemplate()
+			errs = append(errs, fmt.Errorf("mux: route %s: %w", template, err))
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// match returns the route matching the request with the method, or nil if there is none.
+func (r *Router) match(req *http.Request, method string) *Route {
+	return r.matchProbe(req.Clone(req.Context()), method)
+}
+
+// matchProbe is like match, but sets the method of the request, which should be a copy of the request to match.
+func (r *Router) matchProbe(probe *http.Request, method string) *Route {
+	probe.Method = strings.ToUpper(method)
+	var match mux.RouteMatch
+	if !r.Router.Match(probe, &match) || match.MatchErr != nil {
+		return nil
+	}
+	return r.root().routes[match.Route]
+}
+
+// methodOrder is the order of the methods listed in the Allow header, before other methods.
+var methodOrder = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// allowedMethods returns the methods of the routes matching the path of the request,
+// including OPTIONS which is answered automatically, or nil if there is none.
+func (r *Router) allowedMethods(req *http.Request) []string {
+	var allowed []string
+	// each method is matched once, whatever the number of routes
+	probe := req.Clone(req.Context())
+	for _, method := range r.root().methods {
+		if matched := r.matchProbe(probe, method); matched != nil && (method != http.MethodHead || !matched.autoHead || matched.answersHead()) {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	if !slices.Contains(allowed, http.MethodOptions) {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	slices.SortFunc(allowed, func(a, b string) int {
+		i, j := slices.Index(methodOrder, a), slices.Index(methodOrder, b)
+		switch {
+		case i >= 0 && j >= 0:
+			return i - j
+		case i >= 0:
+			return -1
+		case j >= 0:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	return allowed
+}
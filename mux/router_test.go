@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"testing"
+	"testing/fstest"
 )
 
 type staticController struct {
@@ -726,5 +728,235 @@ func TestRouter_OnMethodNotAllowed(t *testing.T) {
 	}
 	r.ServeHTTP(w, req)
 	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
 	assert.JSONEq(t, `{"message": "method not allowed"}`, w.Body.String())
 }
+
+func TestRouter_allowedMethods(t *testing.T) {
+	hello := func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "hello")
+	}
+	r := New()
+	r.Route([]string{"DELETE", "PURGE"}, "/items/{id}", hello)
+	r.Group(&RouteGroup{Prefix: "/items"}, func(router routercontract.Router) {
+		router.GET("/{id}", hello)
+		router.PUT("/{id}", hello)
+	})
+	r.GET("/custom", hello)
+	r.OPTIONS("/custom", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "custom options")
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		w := serve(r, "POST", "/items/1")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD, PUT, DELETE, OPTIONS, PURGE", w.Header().Get("Allow"))
+	})
+
+	t.Run("automatic OPTIONS", func(t *testing.T) {
+		w := serve(r, "OPTIONS", "/items/1")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, HEAD, PUT, DELETE, OPTIONS, PURGE", w.Header().Get("Allow"))
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("registered OPTIONS", func(t *testing.T) {
+		w := serve(r, "OPTIONS", "/custom")
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "custom options", w.Body.String())
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(r, "OPTIONS", "/missing").Code)
+	})
+
+	t.Run("distinct methods", func(t *testing.T) {
+		r.Route([]string{"purge"}, "/purged", hello)
+		assert.Equal(t, []string{"DELETE", "PURGE", "GET", "HEAD", "PUT", "OPTIONS"}, r.methods)
+	})
+}
+
+func TestRouter_WithoutMiddleware(t *testing.T) {
+	t.Run("group router", func(t *testing.T) {
+		r := New()
+		r.Use(new(staticMiddleware))
+		r.Group(&RouteGroup{Prefix: "/public"}, func(router routercontract.Router) {
+			router.(*Router).WithoutMiddleware(new(staticMiddleware))
+			router.GET("/login", func(request *http.Request) responseconstract.Responser {
+				return response.New(200, "login")
+			})
+		})
+		r.GET("/private", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, "private")
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/public/login", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "login", w.Body.String())
+		assert.Equal(t, "", w.Header().Get("Custom-Header"))
+
+		w2 := httptest.NewRecorder()
+		req2, _ := http.NewRequest("GET", "/private", nil)
+		r.ServeHTTP(w2, req2)
+		assert.Equal(t, 403, w2.Code)
+	})
+
+	t.Run("route group field", func(t *testing.T) {
+		r := New()
+		r.Use(new(staticMiddleware), new(nonStaticMiddleware))
+		r.Group(&RouteGroup{Prefix: "/public", WithoutMiddleware: []any{"staticMiddleware", reflect.TypeOf(new(nonStaticMiddleware))}}, func(router routercontract.Router) {
+			router.GET("/login", func(request *http.Request) responseconstract.Responser {
+				return response.New(200, "login")
+			})
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/public/login", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "", w.Header().Get("Custom-Header"))
+		assert.Equal(t, "", w.Header().Get("Request-Method"))
+	})
+
+	t.Run("nested group", func(t *testing.T) {
+		r := New()
+		r.Use(new(staticMiddleware))
+		r.Group(&RouteGroup{Prefix: "/public", WithoutMiddleware: []any{"mux.staticMiddleware"}}, func(router routercontract.Router) {
+			router.Group(&RouteGroup{Prefix: "/nested"}, func(router routercontract.Router) {
+				router.GET("/get", func(request *http.Request) responseconstract.Responser {
+					return response.New(200, "nested")
+				})
+			})
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/public/nested/get", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "nested", w.Body.String())
+	})
+}
+
+func TestRouter_Group_withoutPrefixAndHost(t *testing.T) {
+	r := New()
+	r.Group(&RouteGroup{}, func(router routercontract.Router) {
+		router.Use(new(staticMiddleware))
+		router.GET("/group", func(request *http.Request) responseconstract.Responser {
+			return response.New(200, "group")
+		})
+	})
+	r.GET("/get", func(request *http.Request) responseconstract.Responser {
+		return response.New(200, "get")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/group", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Code)
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/get", nil)
+	r.ServeHTTP(w2, req2)
+	assert.Equal(t, 200, w2.Code)
+	assert.Equal(t, "get", w2.Body.String())
+
+	w3 := httptest.NewRecorder()
+	req3, _ := http.NewRequest("POST", "/get", nil)
+	r.ServeHTTP(w3, req3)
+	assert.Equal(t, 405, w3.Code)
+}
+
+func TestRouter_Handle(t *testing.T) {
+	r := New()
+	r.Use(new(staticMiddleware))
+	r.Handle([]string{"GET"}, "/handle", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(req.Context().Value(idKey).(string)))
+	}))
+	r.Handle([]string{"GET"}, "/raw", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})).(*Route).Raw()
+
+	t.Run("pass", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/handle?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 202, w.Code)
+		assert.Equal(t, "1", w.Body.String())
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+	})
+
+	t.Run("block", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/handle", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 403, w.Code)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/raw", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 202, w.Code)
+		assert.Equal(t, "", w.Header().Get("Custom-Header"))
+	})
+}
+
+func TestRouter_Static(t *testing.T) {
+	r := New()
+	r.Use(new(staticMiddleware))
+	fs := http.FS(fstest.MapFS{"hello.txt": {Data: []byte("Hello World!")}})
+	r.Static("/static/", fs)
+	r.Static("/public/", fs).(*Route).Raw()
+
+	t.Run("pass", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/static/hello.txt?id=1", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "Hello World!", w.Body.String())
+		assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+	})
+
+	t.Run("block", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/static/hello.txt", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 403, w.Code)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/public/hello.txt", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "Hello World!", w.Body.String())
+		assert.Equal(t, "", w.Header().Get("Custom-Header"))
+	})
+}
+
+func TestRouter_Group_lateBoundMiddleware(t *testing.T) {
+	r := New()
+	r.Group(&RouteGroup{Prefix: "/group"}, func(router routercontract.Router) {
+		router.Group(&RouteGroup{Prefix: "/nested"}, func(router routercontract.Router) {
+			router.GET("/get", func(request *http.Request) responseconstract.Responser {
+				return response.New(200).JSON(map[string]any{"id": request.Context().Value(idKey)})
+			})
+		})
+	})
+	r.Use(new(staticMiddleware))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/group/nested/get", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, "After request", w.Header().Get("Custom-Header"))
+
+	w2 := httptest.NewRecorder()
+	req2, _ := http.NewRequest("GET", "/group/nested/get?id=1", nil)
+	r.ServeHTTP(w2, req2)
+	assert.Equal(t, 200, w2.Code)
+	assert.JSONEq(t, `{"id": "1"}`, w2.Body.String())
+}
@@ -0,0 +1,358 @@
+package mux
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	pipelinecontract "github.com/gopi-frame/contract/pipeline"
+	responseconstract "github.com/gopi-frame/contract/response"
+	routercontract "github.com/gopi-frame/contract/router"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span which is propagated across services,
+// see the W3C Trace Context recommendation.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	// Remote reports whether the span context has been received from another service.
+	Remote bool
+}
+
+// IsValid reports whether the span context has a trace ID and a span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as the value of the traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses the value of the traceparent header.
+// Versions other than 00 are parsed as far as version 00 defines them, as the recommendation requires.
+func ParseTraceparent(traceparent string) (SpanContext, bool) {
+	var sc SpanContext
+	if len(traceparent) < 55 || (len(traceparent) > 55 && (traceparent[:2] == "00" || traceparent[55] != '-')) {
+		return sc, false
+	}
+	if traceparent[2] != '-' || traceparent[35] != '-' || traceparent[52] != '-' || traceparent[:2] == "ff" {
+		return sc, false
+	}
+	var version, flags [1]byte
+	if !decodeLowerHex(version[:], traceparent[:2]) ||
+		!decodeLowerHex(sc.TraceID[:], traceparent[3:35]) ||
+		!decodeLowerHex(sc.SpanID[:], traceparent[36:52]) ||
+		!decodeLowerHex(flags[:], traceparent[53:55]) ||
+		!sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	sc.Remote = true
+	return sc, true
+}
+
+// decodeLowerHex decodes the hexadecimal string, which the recommendation requires to be lower case.
+func decodeLowerHex(dst []byte, s string) bool {
+	for i := 0; i < len(s); i++ {
+		if 'A' <= s[i] && s[i] <= 'F' {
+			return false
+		}
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Span is a finished span, as handed to a [SpanExporter].
+type Span struct {
+	Name        string
+	SpanContext SpanContext
+	// Parent is the span context of the parent span, which is invalid for root spans.
+	Parent     SpanContext
+	Start      time.Time
+	End        time.Time
+	Attributes []slog.Attr
+	Err        error
+}
+
+// SpanExporter receives the spans of sampled traces when they end.
+// It is called concurrently, and should not block the request, e.g. by batching spans.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+// InMemoryExporter keeps the exported spans in memory, e.g. for tests and local debugging.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return new(InMemoryExporter)
+}
+
+func (e *InMemoryExporter) ExportSpan(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans, in the order they ended.
+func (e *InMemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Span(nil), e.spans...)
+}
+
+// Reset drops the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// ActiveSpan is a span which has not ended yet.
+// A nil ActiveSpan is valid and does nothing, e.g. when the router has no exporter.
+type ActiveSpan struct {
+	mu       sync.Mutex
+	span     Span
+	exporter SpanExporter
+	ended    bool
+}
+
+type spanKey struct{}
+
+// StartSpan starts a child span of the span in the context, and returns a copy of the context which holds it.
+// If there is no span in the context, it returns the context as is and a nil span.
+func StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *ActiveSpan) {
+	parent, _ := ctx.Value(spanKey{}).(*ActiveSpan)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := newSpan(parent.exporter, name, parent.SpanContext(), attrs)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanFromContext returns the span in the context, or nil if there is none.
+func SpanFromContext(ctx context.Context) *ActiveSpan {
+	span, _ := ctx.Value(spanKey{}).(*ActiveSpan)
+	return span
+}
+
+func newSpan(exporter SpanExporter, name string, parent SpanContext, attrs []slog.Attr) *ActiveSpan {
+	sc := SpanContext{Sampled: true}
+	if parent.IsValid() {
+		sc.TraceID, sc.Sampled, sc.TraceState = parent.TraceID, parent.Sampled, parent.TraceState
+	} else {
+		putRandom(sc.TraceID[:])
+	}
+	putRandom(sc.SpanID[:])
+	return &ActiveSpan{
+		span: Span{
+			Name:        name,
+			SpanContext: sc,
+			Parent:      parent,
+			Start:       time.Now(),
+			Attributes:  attrs,
+		},
+		exporter: exporter,
+	}
+}
+
+// putRandom fills the ID with random bytes, which are not all zero.
+func putRandom(id []byte) {
+	for {
+		for i := 0; i < len(id); i += 8 {
+			v := rand.Uint64()
+			for j := i; j < i+8 && j < len(id); j++ {
+				id[j] = byte(v)
+				v >>= 8
+			}
+		}
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
+
+// SpanContext returns the span context of the span.
+func (s *ActiveSpan) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.span.SpanContext
+}
+
+// SetAttributes adds attributes to the span.
+func (s *ActiveSpan) SetAttributes(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.span.Attributes = append(s.span.Attributes, attrs...)
+}
+
+// SetError records the error the span failed with.
+func (s *ActiveSpan) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.span.Err = err
+}
+
+// End ends the span, and exports it if the trace is sampled. Only the first call has an effect.
+func (s *ActiveSpan) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.span.End = time.Now()
+	span := s.span
+	s.mu.Unlock()
+	if span.SpanContext.Sampled {
+		span.Attributes = append([]slog.Attr(nil), span.Attributes...)
+		s.exporter.ExportSpan(span)
+	}
+}
+
+// SetSpanExporter enables tracing of the requests handled by the router and all its groups,
+// and sets the exporter of their spans. It should be set on the root router.
+//
+// A span is created for every request, as the child of the span of the traceparent and tracestate request headers
+// if there are any, with child spans for every middleware and the handler.
+// The span of the request is stored in the request context, see [StartSpan] and [TraceTransport].
+func (r *Router) SetSpanExporter(exporter SpanExporter) *Router {
+	r.spanExporter = exporter
+	return r
+}
+
+// startSpan starts the span of the request, if the router has an exporter.
+func (r *Route) startSpan(request *http.Request) *ActiveSpan {
+	exporter := r.router.root().spanExporter
+	if exporter == nil {
+		return nil
+	}
+	parent, _ := ParseTraceparent(request.Header.Get("traceparent"))
+	if parent.IsValid() {
+		parent.TraceState = request.Header.Get("tracestate")
+	}
+	template, _ := r.GetPathTemplate()
+	name := request.Method
+	if template != "" {
+		name += " " + template
+	}
+	return newSpan(exporter, name, parent, []slog.Attr{
+		slog.String("http.request.method", request.Method),
+		slog.String("http.route", template),
+		slog.String("url.path", request.URL.Path),
+	})
+}
+
+// endSpan ends the span of the request with the status of the response.
+func (d *dispatch) endSpan(span *ActiveSpan) {
+	status := d.writer.Status()
+	span.SetAttributes(slog.Int("http.response.status_code", status))
+	if name := d.route.GetName(); name != "" {
+		span.SetAttributes(slog.String("mux.route.name", name))
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetError(httpStatusError(status))
+	}
+	span.End()
+}
+
+// httpStatusError is the error of spans of requests which failed with a server error status.
+type httpStatusError int
+
+func (e httpStatusError) Error() string {
+	return http.StatusText(int(e))
+}
+
+// tracePipes wraps the pipes to run each of them in a child span of the span in the request context.
+func tracePipes(pipes []pipelinecontract.Pipe[*http.Request, responseconstract.Responser]) []pipelinecontract.Pipe[*http.Request, responseconstract.Responser] {
+	traced := make([]pipelinecontract.Pipe[*http.Request, responseconstract.Responser], len(pipes))
+	for i, pipe := range pipes {
+		traced[i] = &tracedPipe{pipe: pipe, name: "middleware " + reflect.TypeOf(pipe).String()}
+	}
+	return traced
+}
+
+type tracedPipe struct {
+	pipe pipelinecontract.Pipe[*http.Request, responseconstract.Responser]
+	name string
+}
+
+func (t *tracedPipe) Handle(request *http.Request, next routercontract.Handler) responseconstract.Responser {
+	ctx, span := StartSpan(request.Context(), t.name)
+	defer span.End()
+	return t.pipe.Handle(request.WithContext(ctx), next)
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers to the span in the context, if there is one.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	sc := SpanFromContext(ctx).SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+	header.Set("traceparent", sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set("tracestate", sc.TraceState)
+	}
+}
+
+// TraceTransport wraps the transport to propagate the span of the context of outgoing requests
+// with the traceparent and tracestate headers. If base is nil, [http.DefaultTransport] is used.
+func TraceTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &traceTransport{base: base}
+}
+
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if SpanFromContext(request.Context()) != nil {
+		// a RoundTripper must not modify the request
+		request = request.Clone(request.Context())
+		InjectTraceContext(request.Context(), request.Header)
+	}
+	return t.base.RoundTrip(request)
+}
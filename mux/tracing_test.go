@@ -0,0 +1,146 @@
+package mux
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	responseconstract "github.com/gopi-frame/contract/response"
+	"github.com/gopi-frame/response"
+	"github.com/stretchr/testify/assert"
+)
+
+func spanAttribute(span Span, key string) any {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.Any()
+		}
+	}
+	return nil
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.True(t, sc.Sampled)
+	assert.True(t, sc.Remote)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	sc, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	assert.True(t, ok)
+	assert.False(t, sc.Sampled)
+
+	for _, traceparent := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, ok := ParseTraceparent(traceparent)
+		assert.False(t, ok, traceparent)
+	}
+}
+
+func TestRouter_SetSpanExporter(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	r := New().SetSpanExporter(exporter)
+	r.Use(new(staticMiddleware))
+	r.GET("/users/{id}", func(request *http.Request) responseconstract.Responser {
+		_, span := StartSpan(request.Context(), "query", slog.String("db.system", "sqlite"))
+		span.End()
+		return response.New(200, "hello")
+	}).Name("users.show")
+	r.GET("/fail", func(request *http.Request) responseconstract.Responser {
+		return response.New(http.StatusInternalServerError)
+	})
+
+	t.Run("new trace", func(t *testing.T) {
+		exporter.Reset()
+		serve(r, "GET", "/users/1?id=1")
+		spans := exporter.Spans()
+		if !assert.Len(t, spans, 4) {
+			return
+		}
+		query, handler, middleware, root := spans[0], spans[1], spans[2], spans[3]
+		assert.Equal(t, "GET /users/{id}", root.Name)
+		assert.False(t, root.Parent.IsValid())
+		assert.Equal(t, "GET", spanAttribute(root, "http.request.method"))
+		assert.Equal(t, "/users/{id}", spanAttribute(root, "http.route"))
+		assert.Equal(t, "/users/1", spanAttribute(root, "url.path"))
+		assert.Equal(t, int64(200), spanAttribute(root, "http.response.status_code"))
+		assert.Equal(t, "users.show", spanAttribute(root, "mux.route.name"))
+		assert.NoError(t, root.Err)
+
+		assert.Equal(t, "middleware *mux.staticMiddleware", middleware.Name)
+		assert.Equal(t, root.SpanContext, middleware.Parent)
+		assert.Equal(t, "handler", handler.Name)
+		assert.Equal(t, middleware.SpanContext, handler.Parent)
+		assert.Equal(t, "query", query.Name)
+		assert.Equal(t, handler.SpanContext, query.Parent)
+		assert.Equal(t, "sqlite", spanAttribute(query, "db.system"))
+		for _, span := range spans {
+			assert.Equal(t, root.SpanContext.TraceID, span.SpanContext.TraceID)
+		}
+	})
+
+	t.Run("remote parent", func(t *testing.T) {
+		exporter.Reset()
+		req, _ := http.NewRequest("GET", "/fail?id=1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set("tracestate", "vendor=value")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		spans := exporter.Spans()
+		if !assert.NotEmpty(t, spans) {
+			return
+		}
+		root := spans[len(spans)-1]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", root.Parent.SpanID.String())
+		assert.True(t, root.Parent.Remote)
+		assert.Equal(t, "vendor=value", root.SpanContext.TraceState)
+		assert.Error(t, root.Err)
+	})
+
+	t.Run("not sampled", func(t *testing.T) {
+		exporter.Reset()
+		req, _ := http.NewRequest("GET", "/users/1?id=1", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Empty(t, exporter.Spans())
+	})
+}
+
+func TestTraceTransport(t *testing.T) {
+	var traceparent, tracestate string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent, tracestate = r.Header.Get("traceparent"), r.Header.Get("tracestate")
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	r := New().SetSpanExporter(exporter)
+	var handlerSpan SpanContext
+	r.GET("/proxy", func(request *http.Request) responseconstract.Responser {
+		handlerSpan = SpanFromContext(request.Context()).SpanContext()
+		client := &http.Client{Transport: TraceTransport(nil)}
+		outgoing, _ := http.NewRequestWithContext(request.Context(), "GET", server.URL, nil)
+		resp, err := client.Do(outgoing)
+		if assert.NoError(t, err) {
+			_ = resp.Body.Close()
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/proxy", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=value")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+handlerSpan.SpanID.String()+"-01", traceparent)
+	assert.Equal(t, "vendor=value", tracestate)
+}